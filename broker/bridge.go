@@ -0,0 +1,123 @@
+package broker
+
+import (
+	"slices"
+	"sync"
+)
+
+// Mapper converts a message of the source broker into a message of the destination broker.
+// Returning false drops the message.
+type Mapper[SrcChanT comparable, SrcMsgT any, DstChanT comparable, DstMsgT any] func(channel SrcChanT, msg SrcMsgT) (DstChanT, DstMsgT, bool)
+
+// Bridge forwards messages of selected channels from one broker to another.
+//
+// Forwarded messages are tagged with the brokers they passed through, so a message is never
+// forwarded back into a broker it came from. This makes it safe to bridge brokers both ways
+// or in cycles.
+//
+// Bridge stops when either broker is stopped or Close is called.
+type Bridge[SrcChanT comparable, SrcMsgT any, DstChanT comparable, DstMsgT any] struct {
+	src    *Broker[SrcChanT, SrcMsgT]
+	dst    *Broker[DstChanT, DstMsgT]
+	mapper Mapper[SrcChanT, SrcMsgT, DstChanT, DstMsgT]
+	stop   chan struct{}
+	once   sync.Once
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewBridge creates and starts a new Bridge forwarding given channels of src into dst through mapper.
+// Forwards the default channel of src if no channels are given.
+func NewBridge[SrcChanT comparable, SrcMsgT any, DstChanT comparable, DstMsgT any](
+	src *Broker[SrcChanT, SrcMsgT],
+	dst *Broker[DstChanT, DstMsgT],
+	mapper Mapper[SrcChanT, SrcMsgT, DstChanT, DstMsgT],
+	channels ...SrcChanT,
+) (br *Bridge[SrcChanT, SrcMsgT, DstChanT, DstMsgT]) {
+	br = &Bridge[SrcChanT, SrcMsgT, DstChanT, DstMsgT]{
+		src:    src,
+		dst:    dst,
+		mapper: mapper,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if len(channels) == 0 {
+		channels = []SrcChanT{src.defaultChannel}
+	}
+
+	for _, channel := range channels {
+		sub := newSubscription(src, channel, nil, 5)
+		sub.envCh = make(chan envelope[SrcChanT, SrcMsgT])
+		src.subscribe(sub)
+
+		br.wg.Add(1)
+		go br.run(sub)
+	}
+
+	go func() {
+		br.wg.Wait()
+		close(br.done)
+	}()
+
+	return
+}
+
+// Mirror creates and starts a new Bridge forwarding given channels of src into the same channels of dst.
+func Mirror[ChannelT comparable, MsgT any](src, dst *Broker[ChannelT, MsgT], channels ...ChannelT) *Bridge[ChannelT, MsgT, ChannelT, MsgT] {
+	return NewBridge(src, dst, func(channel ChannelT, msg MsgT) (ChannelT, MsgT, bool) {
+		return channel, msg, true
+	}, channels...)
+}
+
+// run forwards messages of a single source subscription until the bridge stops.
+func (br *Bridge[SrcChanT, SrcMsgT, DstChanT, DstMsgT]) run(sub *Subscription[SrcChanT, SrcMsgT]) {
+	defer br.wg.Done()
+
+	for {
+		select {
+		case env, ok := <-sub.envCh:
+			if !ok {
+				return
+			}
+			br.forward(env)
+		case <-br.dst.done:
+			sub.Close()
+			return
+		case <-br.stop:
+			sub.Close()
+			return
+		}
+	}
+}
+
+// forward maps and publishes the envelope into the destination broker,
+// unless the message has already passed through it.
+func (br *Bridge[SrcChanT, SrcMsgT, DstChanT, DstMsgT]) forward(env envelope[SrcChanT, SrcMsgT]) {
+	if slices.Contains(env.path, br.dst.id) {
+		return
+	}
+
+	channel, msg, ok := br.mapper(env.channel, env.msg)
+	if !ok {
+		return
+	}
+
+	path := make([]uint64, len(env.path), len(env.path)+1)
+	copy(path, env.path)
+
+	fwd := envelope[DstChanT, DstMsgT]{channel: channel, msg: msg, priority: env.priority, path: append(path, br.src.id)}
+
+	br.dst.publishUntil(fwd, br.stop)
+}
+
+// Done returns a channel that is closed when the bridge is stopped.
+func (br *Bridge[SrcChanT, SrcMsgT, DstChanT, DstMsgT]) Done() <-chan struct{} {
+	return br.done
+}
+
+// Close stops the bridge and waits for it to finish.
+func (br *Bridge[SrcChanT, SrcMsgT, DstChanT, DstMsgT]) Close() {
+	br.once.Do(func() { close(br.stop) })
+	<-br.done
+}
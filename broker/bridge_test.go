@@ -0,0 +1,80 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/difof/syncity"
+)
+
+func TestMirror_NoEcho(t *testing.T) {
+	ctx := syncity.NewCancelContextFromBackground()
+	defer ctx.Cancel()
+
+	a := New[string, int](ctx, DefaultChannel)
+	b := New[string, int](ctx, DefaultChannel)
+
+	ab := Mirror(a, b, "testchannel")
+	ba := Mirror(b, a, "testchannel")
+	defer ab.Close()
+	defer ba.Close()
+
+	subA := a.SubscribeChannel("testchannel")
+	subB := b.SubscribeChannel("testchannel")
+
+	a.PublishChannel("testchannel", 1)
+
+	for _, sub := range []*Subscription[string, int]{subA, subB} {
+		select {
+		case msg := <-sub.Channel():
+			if msg != 1 {
+				t.Fatalf("Expected message 1, got %d", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected message to be received")
+		}
+	}
+
+	select {
+	case msg := <-subA.Channel():
+		t.Fatalf("Expected no echo, got %d", msg)
+	case msg := <-subB.Channel():
+		t.Fatalf("Expected no echo, got %d", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNewBridge_Mapper(t *testing.T) {
+	ctx := syncity.NewCancelContextFromBackground()
+	defer ctx.Cancel()
+
+	src := New[string, int](ctx, DefaultChannel)
+	dst := New[int, string](context.Background(), 0)
+
+	br := NewBridge(src, dst, func(channel string, msg int) (int, string, bool) {
+		return len(channel), channel, msg%2 == 0
+	}, "even")
+
+	sub := dst.SubscribeChannel(len("even"))
+
+	src.PublishChannel("even", 1)
+	src.PublishChannel("even", 2)
+
+	select {
+	case msg := <-sub.Channel():
+		if msg != "even" {
+			t.Fatalf("Expected mapped message, got %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected message to be received")
+	}
+
+	ctx.Cancel()
+
+	select {
+	case <-br.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected bridge to stop with the source broker")
+	}
+}
@@ -2,77 +2,198 @@ package broker
 
 import (
 	"context"
-	"github.com/difof/collection"
+	"sync/atomic"
 )
 
 const DefaultChannel = "::"
 
+// pubBuffer is the size of the publish queue of each priority level.
+const pubBuffer = 16
+
+// brokerIds hands out unique ids to brokers, used to tag forwarded messages.
+var brokerIds atomic.Uint64
+
+// envelope carries a message through the broker along with the ids of the brokers
+// it has been forwarded from.
+type envelope[ChannelT comparable, MsgT any] struct {
+	channel  ChannelT
+	msg      MsgT
+	priority Priority
+	path     []uint64
+	// ack is closed once the message is handed to the subscribers, only set by sync brokers.
+	ack chan struct{}
+}
+
 // Broker is a message broadcaster to multiple subscribers (channels).
 type Broker[ChannelT comparable, MsgT any] struct {
-	pub            chan collection.Tuple[ChannelT, MsgT]
+	id             uint64
+	pub            [numPriorities]chan envelope[ChannelT, MsgT]
 	sub            chan *Subscription[ChannelT, MsgT]
 	unsub          chan *Subscription[ChannelT, MsgT]
+	done           chan struct{}
 	defaultChannel ChannelT
+	sync           bool
 }
 
 // New creates and starts a new Broker.
 func New[ChannelT comparable, MsgT any](ctx context.Context, defaultChannel ChannelT) (b *Broker[ChannelT, MsgT]) {
 	b = &Broker[ChannelT, MsgT]{
-		pub:            make(chan collection.Tuple[ChannelT, MsgT], 1),
-		sub:            make(chan *Subscription[ChannelT, MsgT], 1),
-		unsub:          make(chan *Subscription[ChannelT, MsgT], 1),
+		id:             brokerIds.Add(1),
+		sub:            make(chan *Subscription[ChannelT, MsgT]),
+		unsub:          make(chan *Subscription[ChannelT, MsgT]),
+		done:           make(chan struct{}),
 		defaultChannel: defaultChannel,
 	}
 
+	for p := range b.pub {
+		b.pub[p] = make(chan envelope[ChannelT, MsgT], pubBuffer)
+	}
+
 	go b.start(ctx)
 
 	return
 }
 
+// NewSync creates and starts a new synchronous Broker.
+// Publishing returns only after the message is handed to all subscribers, which makes
+// the broker deterministic and useful in tests.
+func NewSync[ChannelT comparable, MsgT any](ctx context.Context, defaultChannel ChannelT) (b *Broker[ChannelT, MsgT]) {
+	b = New[ChannelT, MsgT](ctx, defaultChannel)
+	b.sync = true
+	return
+}
+
 func NewFrom[MsgT any](ctx context.Context, _ MsgT) *Broker[string, MsgT] {
 	return New[string, MsgT](ctx, DefaultChannel)
 }
 
 // start starts the broker. Must be called before adding any new subscribers.
 // Will block until the broker is stopped.
+//
+// Pending messages are dispatched by priority, see scheduler.
 func (b *Broker[ChannelT, MsgT]) start(ctx context.Context) {
 	subs := map[ChannelT]map[*Subscription[ChannelT, MsgT]]struct{}{}
+	sched := scheduler{}
+
+	stop := func() {
+		for _, subs := range subs {
+			for sub := range subs {
+				sub.close(ErrStopped)
+			}
+		}
+		close(b.done)
+	}
+
+	add := func(sub *Subscription[ChannelT, MsgT]) {
+		if _, ok := subs[sub.channel]; !ok {
+			subs[sub.channel] = map[*Subscription[ChannelT, MsgT]]struct{}{}
+		}
+		subs[sub.channel][sub] = struct{}{}
+		sub.start()
+	}
+
+	remove := func(unsub *Subscription[ChannelT, MsgT]) {
+		if _, ok := subs[unsub.channel][unsub]; ok {
+			delete(subs[unsub.channel], unsub)
+			unsub.close(ErrUnsubscribed)
+		}
+	}
+
+	dispatch := func(env envelope[ChannelT, MsgT]) {
+		for sub := range subs[env.channel] {
+			sub.deliver(env)
+		}
+		if env.ack != nil {
+			close(env.ack)
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			for _, subs := range subs {
-				for sub := range subs {
-					close(sub.msgCh)
-				}
-			}
+			stop()
 			return
 		case sub := <-b.sub:
-			if _, ok := subs[sub.channel]; !ok {
-				subs[sub.channel] = map[*Subscription[ChannelT, MsgT]]struct{}{}
-			}
-			subs[sub.channel][sub] = struct{}{}
+			add(sub)
 		case unsub := <-b.unsub:
-			delete(subs[unsub.channel], unsub)
-		case msg := <-b.pub:
-			for sub := range subs[msg.Key()] {
-				select {
-				case sub.msgCh <- msg.Value():
-				default:
-				}
+			remove(unsub)
+		default:
+			// only the broker receives from pub, so a pending message is always there to take
+			if p, ok := sched.pick(func(p Priority) bool { return len(b.pub[p]) > 0 }); ok {
+				dispatch(<-b.pub[p])
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				stop()
+				return
+			case sub := <-b.sub:
+				add(sub)
+			case unsub := <-b.unsub:
+				remove(unsub)
+			case env := <-b.pub[PriorityHigh]:
+				dispatch(env)
+			case env := <-b.pub[PriorityNormal]:
+				dispatch(env)
+			case env := <-b.pub[PriorityLow]:
+				dispatch(env)
 			}
 		}
 	}
 }
 
+// publish hands the envelope to the broker, dropping it if the broker is stopped.
+// Waits for the delivery if the broker is synchronous.
+func (b *Broker[ChannelT, MsgT]) publish(env envelope[ChannelT, MsgT]) {
+	b.publishUntil(env, nil)
+}
+
+// publishUntil is same as publish, giving up once stop is closed.
+func (b *Broker[ChannelT, MsgT]) publishUntil(env envelope[ChannelT, MsgT], stop <-chan struct{}) {
+	if b.sync {
+		env.ack = make(chan struct{})
+	}
+
+	select {
+	case b.pub[env.priority] <- env:
+	case <-b.done:
+		return
+	case <-stop:
+		return
+	}
+
+	if env.ack != nil {
+		select {
+		case <-env.ack:
+		case <-b.done:
+		case <-stop:
+		}
+	}
+}
+
+// subscribe registers the subscription, closing it right away if the broker is stopped.
+func (b *Broker[ChannelT, MsgT]) subscribe(sub *Subscription[ChannelT, MsgT]) *Subscription[ChannelT, MsgT] {
+	select {
+	case b.sub <- sub:
+	case <-b.done:
+		sub.start()
+		sub.close(ErrStopped)
+	}
+
+	return sub
+}
+
 // Publish publishes a message to the broker on default channel.
-func (b *Broker[ChannelT, MsgT]) Publish(msg MsgT) {
-	b.pub <- collection.NewTuple(b.defaultChannel, msg)
+// Supply zero or one priority, no priority will be counted as PriorityNormal.
+func (b *Broker[ChannelT, MsgT]) Publish(msg MsgT, priority ...Priority) {
+	b.PublishChannel(b.defaultChannel, msg, priority...)
 }
 
 // PublishChannel publishes a message to the broker.
-func (b *Broker[ChannelT, MsgT]) PublishChannel(channel ChannelT, msg MsgT) {
-	b.pub <- collection.NewTuple(channel, msg)
+// Supply zero or one priority, no priority will be counted as PriorityNormal.
+func (b *Broker[ChannelT, MsgT]) PublishChannel(channel ChannelT, msg MsgT, priority ...Priority) {
+	b.publish(envelope[ChannelT, MsgT]{channel: channel, msg: msg, priority: priorityOf(priority)})
 }
 
 // Subscribe subscribes to the broker on default channel.
@@ -82,13 +203,32 @@ func (b *Broker[ChannelT, MsgT]) Subscribe() *Subscription[ChannelT, MsgT] {
 
 // SubscribeChannel subscribes to the broker.
 func (b *Broker[ChannelT, MsgT]) SubscribeChannel(channel ChannelT) *Subscription[ChannelT, MsgT] {
-	sub := NewSubscription(b, channel, make(chan MsgT, 5))
-	b.sub <- sub
+	return b.SubscribeBuffer(channel, 5)
+}
+
+// SubscribeBuffer subscribes to the broker with given buffer size.
+// While the buffer is full, a message evicts the oldest queued message of a lower priority,
+// or is dropped for the subscriber if there is none.
+func (b *Broker[ChannelT, MsgT]) SubscribeBuffer(channel ChannelT, size int) *Subscription[ChannelT, MsgT] {
+	return b.subscribe(newSubscription(b, channel, make(chan MsgT), size))
+}
+
+// SubscribeContext subscribes to the broker. The subscription is closed when ctx is done.
+func (b *Broker[ChannelT, MsgT]) SubscribeContext(ctx context.Context, channel ChannelT) *Subscription[ChannelT, MsgT] {
+	sub := b.SubscribeChannel(channel)
+	sub.stop = context.AfterFunc(ctx, func() { b.Unsubscribe(sub) })
 	return sub
 }
 
-// Unsubscribe unsubscribes from the broker.
+// Unsubscribe unsubscribes from the broker. The subscription channel is closed by the broker.
 func (b *Broker[ChannelT, MsgT]) Unsubscribe(sub *Subscription[ChannelT, MsgT]) {
-	b.unsub <- sub
-	close(sub.msgCh)
+	select {
+	case b.unsub <- sub:
+	case <-b.done:
+	}
+}
+
+// Done returns a channel that is closed when the broker is stopped.
+func (b *Broker[ChannelT, MsgT]) Done() <-chan struct{} {
+	return b.done
 }
@@ -0,0 +1,38 @@
+// Package brokertest provides utilities for deterministic testing of broker based code.
+package brokertest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/difof/syncity/broker"
+)
+
+// New creates a synchronous broker which is stopped when the test finishes.
+// The test fails if any goroutine started during the test is still running after the broker is stopped.
+//
+// Subscribing, unsubscribing and publishing all take effect before returning,
+// so there is no need to wait for subscriptions to register.
+// Messages forwarded by a broker.Bridge into the broker are published by the bridge once it
+// receives them, after the publish on the source returned, so use the waiting assertions of Recorder for them.
+func New[ChannelT comparable, MsgT any](t testing.TB, defaultChannel ChannelT) *broker.Broker[ChannelT, MsgT] {
+	t.Helper()
+
+	checkLeaks := CheckLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	b := broker.NewSync[ChannelT, MsgT](ctx, defaultChannel)
+
+	t.Cleanup(func() {
+		cancel()
+		<-b.Done()
+		checkLeaks()
+	})
+
+	return b
+}
+
+// NewFrom is same as New with string channels and broker.DefaultChannel as default channel.
+func NewFrom[MsgT any](t testing.TB, _ MsgT) *broker.Broker[string, MsgT] {
+	t.Helper()
+	return New[string, MsgT](t, broker.DefaultChannel)
+}
@@ -0,0 +1,39 @@
+package brokertest
+
+import (
+	"testing"
+
+	"github.com/difof/syncity/broker"
+)
+
+func TestRecorder(t *testing.T) {
+	b := NewFrom(t, "")
+
+	r := Record(t, b, "testchannel")
+	other := Record(t, b, broker.DefaultChannel)
+
+	b.PublishChannel("testchannel", "first")
+	b.PublishChannel("testchannel", "second")
+	b.PublishChannel("testchannel", "third")
+
+	r.ExpectMessage("first")
+	if got := r.WaitFor(3); got[2] != "third" {
+		t.Fatalf("Expected third message to be %q, got %q", "third", got[2])
+	}
+
+	r.ExpectNoMessage(0)
+	other.ExpectNoMessage(0)
+}
+
+func TestNew_Bridge(t *testing.T) {
+	src := NewFrom(t, 0)
+	dst := NewFrom(t, 0)
+
+	br := broker.Mirror(src, dst)
+	t.Cleanup(br.Close)
+
+	r := Record(t, dst, broker.DefaultChannel)
+
+	src.Publish(1)
+	r.ExpectMessage(1)
+}
@@ -0,0 +1,72 @@
+package brokertest
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// LeakTimeout is how long CheckLeaks waits for goroutines to exit before reporting them.
+var LeakTimeout = time.Second
+
+// CheckLeaks snapshots the running goroutines and returns a function which fails the test
+// if any goroutine started since the snapshot is still running after LeakTimeout.
+func CheckLeaks(t testing.TB) func() {
+	before := goroutines()
+
+	return func() {
+		t.Helper()
+
+		var leaked []string
+		deadline := time.Now().Add(LeakTimeout)
+
+		for {
+			leaked = leaked[:0]
+			for id, stack := range goroutines() {
+				if _, ok := before[id]; !ok && !ignoredGoroutine(stack) {
+					leaked = append(leaked, stack)
+				}
+			}
+
+			if len(leaked) == 0 || time.Now().After(deadline) {
+				break
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if len(leaked) > 0 {
+			t.Errorf("found %d leaked goroutines:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+	}
+}
+
+// goroutines returns the stack traces of all running goroutines by their header, e.g. "goroutine 7".
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := map[string]string{}
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		header, _, _ := strings.Cut(string(stack), " [")
+		stacks[header] = string(stack)
+	}
+
+	return stacks
+}
+
+// ignoredGoroutine reports whether the goroutine belongs to the test runner rather than the test.
+func ignoredGoroutine(stack string) bool {
+	return strings.Contains(stack, "testing.(*T).Run") ||
+		strings.Contains(stack, "testing.tRunner") ||
+		strings.Contains(stack, "created by runtime.")
+}
@@ -0,0 +1,112 @@
+package brokertest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/difof/syncity/broker"
+)
+
+// RecorderBuffer is the buffer size of the subscriptions created by Record.
+const RecorderBuffer = 1024
+
+// Recorder is a subscription which records received messages and provides assertions on them.
+type Recorder[ChannelT comparable, MsgT any] struct {
+	t        testing.TB
+	sub      *broker.Subscription[ChannelT, MsgT]
+	received []MsgT
+	// Timeout is how long assertions wait for a message. Defaults to one second.
+	Timeout time.Duration
+}
+
+// Record subscribes a new Recorder to the channel. The subscription is closed when the test finishes.
+func Record[ChannelT comparable, MsgT any](t testing.TB, b *broker.Broker[ChannelT, MsgT], channel ChannelT) *Recorder[ChannelT, MsgT] {
+	r := &Recorder[ChannelT, MsgT]{
+		t:       t,
+		sub:     b.SubscribeBuffer(channel, RecorderBuffer),
+		Timeout: time.Second,
+	}
+
+	t.Cleanup(r.sub.Close)
+
+	return r
+}
+
+// next receives the next message, waiting at most wait.
+func (r *Recorder[ChannelT, MsgT]) next(wait time.Duration) (msg MsgT, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+
+	if msg, err = r.sub.Recv(ctx); err == nil {
+		r.received = append(r.received, msg)
+	}
+
+	return
+}
+
+// Received returns all messages received so far by the assertions.
+func (r *Recorder[ChannelT, MsgT]) Received() []MsgT {
+	return r.received
+}
+
+// Subscription returns the underlying subscription.
+func (r *Recorder[ChannelT, MsgT]) Subscription() *broker.Subscription[ChannelT, MsgT] {
+	return r.sub
+}
+
+// ExpectMessage fails the test if the next message is not deeply equal to want.
+func (r *Recorder[ChannelT, MsgT]) ExpectMessage(want MsgT) MsgT {
+	r.t.Helper()
+
+	msg, err := r.next(r.Timeout)
+	if err != nil {
+		r.t.Fatalf("expected message %v, got error: %v", want, err)
+	}
+
+	if !reflect.DeepEqual(msg, want) {
+		r.t.Fatalf("expected message %v, got %v", want, msg)
+	}
+
+	return msg
+}
+
+// ExpectNoMessage fails the test if a message is received within wait.
+// With synchronous brokers a zero wait is enough since publishing has already taken effect.
+func (r *Recorder[ChannelT, MsgT]) ExpectNoMessage(wait time.Duration) {
+	r.t.Helper()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	// check the queue first, so a zero wait never wins against a pending message
+	if r.sub.Len() > 0 {
+		if msg, err := r.next(r.Timeout); err == nil {
+			r.t.Fatalf("expected no message, got %v", msg)
+		}
+	}
+
+	select {
+	case msg, ok := <-r.sub.Messages():
+		if ok {
+			r.t.Fatalf("expected no message, got %v", msg)
+		}
+	case <-timer.C:
+	}
+}
+
+// WaitFor receives messages until n messages are received in total and returns them.
+// Fails the test if they are not received within Timeout.
+func (r *Recorder[ChannelT, MsgT]) WaitFor(n int) []MsgT {
+	r.t.Helper()
+
+	deadline := time.Now().Add(r.Timeout)
+	for len(r.received) < n {
+		if _, err := r.next(time.Until(deadline)); err != nil {
+			r.t.Fatalf("expected %d messages, got %d: %v", n, len(r.received), err)
+		}
+	}
+
+	return r.received[:n]
+}
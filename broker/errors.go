@@ -0,0 +1,11 @@
+package broker
+
+import "errors"
+
+var (
+	// ErrUnsubscribed is returned by Subscription.Recv after the subscription is closed.
+	ErrUnsubscribed = errors.New("broker: unsubscribed")
+
+	// ErrStopped is returned by Subscription.Recv after the broker is stopped.
+	ErrStopped = errors.New("broker: stopped")
+)
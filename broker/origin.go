@@ -0,0 +1,28 @@
+package broker
+
+import "context"
+
+// Origin identifies a publisher outside of the broker, such as an event bus forwarding into it,
+// so that its own messages can be told apart when they come back. See PublishFrom and SubscribeExcept.
+type Origin uint64
+
+// NewOrigin returns a new unique Origin. Origins never collide with the ids of brokers.
+func NewOrigin() Origin {
+	return Origin(brokerIds.Add(1))
+}
+
+// PublishFrom is same as PublishChannel, tagging the message with origin.
+// The tag is kept when the message is forwarded by bridges.
+func (b *Broker[ChannelT, MsgT]) PublishFrom(origin Origin, channel ChannelT, msg MsgT, priority ...Priority) {
+	b.publish(envelope[ChannelT, MsgT]{channel: channel, msg: msg, priority: priorityOf(priority), path: []uint64{uint64(origin)}})
+}
+
+// SubscribeExcept is same as SubscribeContext, dropping the messages published by origin
+// directly or through bridges.
+func (b *Broker[ChannelT, MsgT]) SubscribeExcept(ctx context.Context, channel ChannelT, origin Origin) *Subscription[ChannelT, MsgT] {
+	sub := newSubscription(b, channel, make(chan MsgT), 5)
+	sub.except = uint64(origin)
+	b.subscribe(sub)
+	sub.stop = context.AfterFunc(ctx, func() { b.Unsubscribe(sub) })
+	return sub
+}
@@ -0,0 +1,36 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscribeExcept(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	a := NewSync[string, int](ctx, DefaultChannel)
+	b := NewSync[string, int](ctx, DefaultChannel)
+
+	ab := Mirror(a, b, "testchannel")
+	defer ab.Close()
+
+	origin := NewOrigin()
+	except := b.SubscribeExcept(ctx, "testchannel", origin)
+	all := b.SubscribeChannel("testchannel")
+
+	// the tag of origin survives the bridge
+	a.PublishFrom(origin, "testchannel", 1)
+	a.PublishChannel("testchannel", 2)
+
+	for _, want := range []int{1, 2} {
+		if msg, err := all.Recv(ctx); err != nil || msg != want {
+			t.Fatalf("Expected message %d, got %d (%v)", want, msg, err)
+		}
+	}
+
+	if msg, err := except.Recv(ctx); err != nil || msg != 2 {
+		t.Fatalf("Expected message 2 only, got %d (%v)", msg, err)
+	}
+}
@@ -0,0 +1,126 @@
+package broker
+
+// Priority of a published message. Higher priority messages are dispatched and delivered first.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+// starvationLimit is how many times a priority level with pending messages may be passed over
+// in favor of higher levels before it is served anyway.
+const starvationLimit = 8
+
+// priorityOf returns the first of the optional priorities, or PriorityNormal if none is given.
+func priorityOf(priority []Priority) Priority {
+	if len(priority) == 0 {
+		return PriorityNormal
+	}
+
+	p := priority[0]
+	if p < PriorityLow {
+		return PriorityLow
+	}
+	if p > PriorityHigh {
+		return PriorityHigh
+	}
+
+	return p
+}
+
+// scheduler picks which priority level to serve next, highest first,
+// unless a lower level has been starved for too long.
+type scheduler struct {
+	skipped [numPriorities]int
+}
+
+// pick returns the level to serve among the levels with pending messages.
+func (s *scheduler) pick(pending func(Priority) bool) (chosen Priority, ok bool) {
+	for p := PriorityHigh; p >= PriorityLow; p-- {
+		if pending(p) {
+			chosen, ok = p, true
+			break
+		}
+	}
+
+	if !ok {
+		return
+	}
+
+	for p := PriorityLow; p < chosen; p++ {
+		if pending(p) && s.skipped[p] >= starvationLimit {
+			chosen = p
+			break
+		}
+	}
+
+	for p := PriorityLow; p < chosen; p++ {
+		if pending(p) {
+			s.skipped[p]++
+		}
+	}
+	s.skipped[chosen] = 0
+
+	return
+}
+
+// priorityQueue is a bounded FIFO queue per priority level.
+type priorityQueue[T any] struct {
+	levels [numPriorities][]T
+	len    int
+	cap    int
+	sched  scheduler
+}
+
+func newPriorityQueue[T any](cap int) *priorityQueue[T] {
+	return &priorityQueue[T]{cap: max(cap, 1)}
+}
+
+// push adds v to the queue. When the queue is full, the oldest item of the lowest level
+// below p is evicted to make room, otherwise v is dropped and false is returned.
+func (q *priorityQueue[T]) push(p Priority, v T) bool {
+	if q.len >= q.cap {
+		evicted := false
+		for l := PriorityLow; l < p; l++ {
+			if len(q.levels[l]) > 0 {
+				q.levels[l] = q.levels[l][1:]
+				q.len--
+				evicted = true
+				break
+			}
+		}
+
+		if !evicted {
+			return false
+		}
+	}
+
+	q.levels[p] = append(q.levels[p], v)
+	q.len++
+
+	return true
+}
+
+// pop removes and returns the next item to be delivered.
+func (q *priorityQueue[T]) pop() (v T, ok bool) {
+	p, ok := q.sched.pick(func(p Priority) bool { return len(q.levels[p]) > 0 })
+	if !ok {
+		return
+	}
+
+	v = q.levels[p][0]
+	q.levels[p] = q.levels[p][1:]
+	q.len--
+
+	return
+}
+
+// clear removes all items.
+func (q *priorityQueue[T]) clear() {
+	q.levels = [numPriorities][]T{}
+	q.len = 0
+}
@@ -0,0 +1,78 @@
+package broker
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/difof/syncity"
+)
+
+func TestSubscription_Priority(t *testing.T) {
+	ctx := syncity.NewCancelContextFromBackground()
+	defer ctx.Cancel()
+
+	b := NewSync[string, string](ctx, DefaultChannel)
+	sub := b.SubscribeBuffer(DefaultChannel, 10)
+
+	b.Publish("low1", PriorityLow)
+	b.Publish("low2", PriorityLow)
+	b.Publish("low3", PriorityLow)
+	b.Publish("high", PriorityHigh)
+
+	recvCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	received := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		msg, err := sub.Recv(recvCtx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, msg)
+	}
+
+	// the first low message may already be on its way before the high one is published
+	if slices.Index(received, "high") > 1 {
+		t.Fatalf("Expected high priority message to be delivered first, got %v", received)
+	}
+}
+
+func TestPriorityQueue_Evict(t *testing.T) {
+	q := newPriorityQueue[string](2)
+
+	q.push(PriorityLow, "low1")
+	q.push(PriorityLow, "low2")
+
+	if q.push(PriorityLow, "low3") {
+		t.Fatal("Expected message to be dropped on full queue")
+	}
+
+	if !q.push(PriorityHigh, "high") {
+		t.Fatal("Expected high priority message to evict a low priority one")
+	}
+
+	if v, _ := q.pop(); v != "high" {
+		t.Fatalf("Expected %q, got %q", "high", v)
+	}
+
+	if v, _ := q.pop(); v != "low2" {
+		t.Fatalf("Expected %q, got %q", "low2", v)
+	}
+}
+
+func TestScheduler_Starvation(t *testing.T) {
+	s := scheduler{}
+	pending := func(Priority) bool { return true }
+
+	for i := 0; i < starvationLimit; i++ {
+		if p, _ := s.pick(pending); p != PriorityHigh {
+			t.Fatalf("Expected pick %d to be high priority, got %d", i, p)
+		}
+	}
+
+	if p, _ := s.pick(pending); p != PriorityLow {
+		t.Fatalf("Expected starved low priority to be picked, got %d", p)
+	}
+}
@@ -1,27 +1,174 @@
 package broker
 
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
 type Subscription[ChannelT comparable, MsgT any] struct {
 	channel ChannelT
-	// TODO: use buffered channel
-	msgCh  chan MsgT
+	msgCh   chan MsgT
+	// envCh replaces msgCh for internal subscriptions which need the whole envelope.
+	envCh  chan envelope[ChannelT, MsgT]
 	broker *Broker[ChannelT, MsgT]
+	// err is the reason the subscription was closed, set before closing the channel.
+	err error
+	// stop releases the context watch of SubscribeContext.
+	stop func() bool
+	// except is the origin whose messages are dropped, see SubscribeExcept.
+	except uint64
+
+	lock     sync.Mutex
+	queue    *priorityQueue[envelope[ChannelT, MsgT]]
+	inflight int
+	signal   chan struct{}
+	closing  chan struct{}
 }
 
+// NewSubscription creates a new subscription which queues up to cap(msgCh) messages before delivering them to msgCh.
 func NewSubscription[ChannelT comparable, MsgT any](broker *Broker[ChannelT, MsgT], channel ChannelT, msgCh chan MsgT) *Subscription[ChannelT, MsgT] {
+	return newSubscription(broker, channel, msgCh, cap(msgCh))
+}
+
+func newSubscription[ChannelT comparable, MsgT any](broker *Broker[ChannelT, MsgT], channel ChannelT, msgCh chan MsgT, size int) *Subscription[ChannelT, MsgT] {
 	return &Subscription[ChannelT, MsgT]{
 		channel: channel,
 		msgCh:   msgCh,
 		broker:  broker,
+		queue:   newPriorityQueue[envelope[ChannelT, MsgT]](size),
+		signal:  make(chan struct{}, 1),
+		closing: make(chan struct{}),
 	}
 }
 
+// start starts delivering queued messages. Only called by the broker.
+func (s *Subscription[ChannelT, MsgT]) start() {
+	go s.pump()
+}
+
+// pump delivers queued messages by priority until the subscription is closed.
+func (s *Subscription[ChannelT, MsgT]) pump() {
+	defer s.closeChannel()
+
+	for {
+		s.lock.Lock()
+		env, ok := s.queue.pop()
+		if ok {
+			s.inflight = 1
+		}
+		s.lock.Unlock()
+
+		if !ok {
+			select {
+			case <-s.signal:
+				continue
+			case <-s.closing:
+				return
+			}
+		}
+
+		if s.envCh != nil {
+			select {
+			case s.envCh <- env:
+			case <-s.closing:
+				return
+			}
+		} else {
+			select {
+			case s.msgCh <- env.msg:
+			case <-s.closing:
+				return
+			}
+		}
+
+		s.lock.Lock()
+		s.inflight = 0
+		s.lock.Unlock()
+	}
+}
+
+// closeChannel drops the queued messages and closes the subscription channel.
+func (s *Subscription[ChannelT, MsgT]) closeChannel() {
+	s.lock.Lock()
+	s.queue.clear()
+	s.inflight = 0
+	s.lock.Unlock()
+
+	if s.envCh != nil {
+		close(s.envCh)
+		return
+	}
+
+	close(s.msgCh)
+}
+
+// deliver queues the envelope for the subscriber, see priorityQueue.push for when the queue is full.
+func (s *Subscription[ChannelT, MsgT]) deliver(env envelope[ChannelT, MsgT]) {
+	if s.except != 0 && slices.Contains(env.path, s.except) {
+		return
+	}
+
+	s.lock.Lock()
+	s.queue.push(env.priority, env)
+	s.lock.Unlock()
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// close closes the subscription with the given reason. Only called by the broker.
+func (s *Subscription[ChannelT, MsgT]) close(reason error) {
+	s.err = reason
+	close(s.closing)
+}
+
 // Channel returns the channel of the subscription.
+//
+// Deprecated: Use Messages or Recv. Sending into or closing the returned channel breaks the broker.
 func (s *Subscription[ChannelT, MsgT]) Channel() chan MsgT {
 	return s.msgCh
 }
 
+// Messages returns the receive-only channel of the subscription.
+// The channel is closed when the subscription or the broker is closed.
+func (s *Subscription[ChannelT, MsgT]) Messages() <-chan MsgT {
+	return s.msgCh
+}
+
+// Len returns the number of messages waiting to be received.
+func (s *Subscription[ChannelT, MsgT]) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.queue.len + s.inflight + len(s.msgCh)
+}
+
+// Recv blocks until a message is received.
+// Returns ctx.Err() if ctx is done, ErrUnsubscribed if the subscription is closed
+// and ErrStopped if the broker is stopped.
+func (s *Subscription[ChannelT, MsgT]) Recv(ctx context.Context) (msg MsgT, err error) {
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case m, ok := <-s.msgCh:
+		if !ok {
+			err = s.err
+			return
+		}
+		msg = m
+	}
+
+	return
+}
+
 // Close removes the subscription.
 func (s *Subscription[ChannelT, MsgT]) Close() {
+	if s.stop != nil {
+		s.stop()
+	}
+
 	s.broker.Unsubscribe(s)
 }
 
@@ -0,0 +1,60 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/difof/syncity"
+)
+
+func TestSubscription_Recv(t *testing.T) {
+	ctx := syncity.NewCancelContextFromBackground()
+	b := New[string, int](ctx, DefaultChannel)
+
+	sub := b.Subscribe()
+	b.Publish(1)
+
+	recvCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if msg, err := sub.Recv(recvCtx); err != nil || msg != 1 {
+		t.Fatalf("Expected message 1, got %d (%v)", msg, err)
+	}
+
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shortCancel()
+
+	if _, err := sub.Recv(shortCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	sub.Close()
+	if _, err := sub.Recv(recvCtx); !errors.Is(err, ErrUnsubscribed) {
+		t.Fatalf("Expected %v, got %v", ErrUnsubscribed, err)
+	}
+
+	stopped := b.Subscribe()
+	ctx.Cancel()
+	if _, err := stopped.Recv(recvCtx); !errors.Is(err, ErrStopped) {
+		t.Fatalf("Expected %v, got %v", ErrStopped, err)
+	}
+}
+
+func TestBroker_SubscribeContext(t *testing.T) {
+	b := NewFrom(context.Background(), 0)
+
+	subCtx, cancel := context.WithCancel(context.Background())
+	sub := b.SubscribeContext(subCtx, DefaultChannel)
+	cancel()
+
+	select {
+	case _, ok := <-sub.Messages():
+		if ok {
+			t.Fatal("Expected subscription channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected subscription to be closed with its context")
+	}
+}
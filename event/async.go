@@ -0,0 +1,119 @@
+package event
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// asyncJob is an event waiting in the queue of an async bus.
+type asyncJob struct {
+	ctx   context.Context
+	topic string
+	event Event
+}
+
+// asyncDispatcher delivers queued events on a pool of workers.
+// Each topic is always handled by the same worker, which preserves the per-topic ordering.
+type asyncDispatcher struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	shards  []chan asyncJob
+	lock    sync.Mutex
+	pending int
+	idle    chan struct{}
+}
+
+func newAsyncDispatcher(ctx context.Context, workers, queueSize int) (d *asyncDispatcher) {
+	ctx, cancel := context.WithCancel(ctx)
+	d = &asyncDispatcher{
+		ctx:    ctx,
+		cancel: cancel,
+		shards: make([]chan asyncJob, max(workers, 1)),
+		idle:   make(chan struct{}),
+	}
+
+	close(d.idle)
+
+	for i := range d.shards {
+		d.shards[i] = make(chan asyncJob, max(queueSize, 1))
+	}
+
+	return
+}
+
+// start runs the workers, each calling handle for the jobs of its queue until ctx is done.
+func (d *asyncDispatcher) start(handle func(ctx context.Context, topic string, event Event)) {
+	for _, shard := range d.shards {
+		go func(shard chan asyncJob) {
+			for {
+				select {
+				case <-d.ctx.Done():
+					return
+				case job := <-shard:
+					handle(job.ctx, job.topic, job.event)
+					d.done()
+				}
+			}
+		}(shard)
+	}
+}
+
+// shard returns the queue of the topic.
+func (d *asyncDispatcher) shard(topic string) chan asyncJob {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(topic))
+	return d.shards[h.Sum32()%uint32(len(d.shards))]
+}
+
+// enqueue queues the event, blocking while the queue of the topic is full.
+// Handlers get the values of ctx but not its cancellation, since the publisher may be long gone.
+// Returns ctx.Err() of the bus if it is stopped.
+func (d *asyncDispatcher) enqueue(ctx context.Context, topic string, event Event) error {
+	d.lock.Lock()
+	if d.pending == 0 {
+		d.idle = make(chan struct{})
+	}
+	d.pending++
+	d.lock.Unlock()
+
+	select {
+	case d.shard(topic) <- asyncJob{ctx: context.WithoutCancel(ctx), topic: topic, event: event}:
+		return nil
+	case <-d.ctx.Done():
+		d.done()
+		return d.ctx.Err()
+	}
+}
+
+// done marks a queued event as handled.
+func (d *asyncDispatcher) done() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.pending--
+	if d.pending == 0 {
+		close(d.idle)
+	}
+}
+
+// flush waits until all queued events are handled or ctx is done.
+func (d *asyncDispatcher) flush(ctx context.Context) error {
+	d.lock.Lock()
+	idle := d.idle
+	d.lock.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.ctx.Done():
+		return d.ctx.Err()
+	}
+}
+
+// stop stops the workers, dropping the queued events.
+func (d *asyncDispatcher) stop() {
+	d.cancel()
+}
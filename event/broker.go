@@ -0,0 +1,87 @@
+package event
+
+import (
+	"context"
+
+	"github.com/difof/syncity/broker"
+)
+
+// fromBrokerKey marks the context of events published by FromBroker with the broker they came from.
+type fromBrokerKey struct{}
+
+// ToBroker forwards events published on topic of the bus into channel of the broker.
+// mapper converts the event into a broker message, returning false drops the event.
+// Forwarding stops when ctx is done.
+//
+// Events published into the bus by FromBroker of the same broker are not forwarded back, and
+// FromBroker drops the messages forwarded by ToBroker, so the two can be paired on the same topic and
+// channel to share a single stream between the bus and the broker.
+func ToBroker[ChannelT comparable, MsgT any](
+	ctx context.Context,
+	bus *Bus, topic string,
+	b *broker.Broker[ChannelT, MsgT], channel ChannelT,
+	mapper func(Event) (MsgT, bool),
+) *Subscription {
+	origin := bus.brokerOrigin()
+
+	return bus.SubscribeContext(ctx, topic, func(ctx context.Context, e Event) error {
+		if from := ctx.Value(fromBrokerKey{}); from == any(b) {
+			return nil
+		}
+
+		if msg, ok := mapper(e); ok {
+			b.PublishFrom(origin, channel, msg)
+		}
+		return nil
+	})
+}
+
+// FromBroker publishes messages received on channel of the broker into topic of the bus with ctx.
+// mapper converts the message into an event, returning false drops the message.
+// Messages forwarded into the broker by ToBroker of the same bus are dropped, see ToBroker.
+//
+// FromBroker is a blocking function. Returns nil when ctx is done, or broker.ErrStopped when
+// the broker is stopped. Errors of publishing into the bus are passed to the function set by
+// Bus.SetErrorHandler, forwarding goes on with the next message.
+func FromBroker[ChannelT comparable, MsgT any](
+	ctx context.Context,
+	b *broker.Broker[ChannelT, MsgT], channel ChannelT,
+	bus *Bus, topic string,
+	mapper func(MsgT) (Event, bool),
+) error {
+	sub := b.SubscribeExcept(ctx, channel, bus.brokerOrigin())
+	defer sub.Close()
+
+	pctx := context.WithValue(ctx, fromBrokerKey{}, any(b))
+
+	for {
+		msg, err := sub.Recv(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if e, ok := mapper(msg); ok {
+			if err = bus.PublishContext(pctx, topic, e); err != nil {
+				bus.handleError(err)
+			}
+		}
+	}
+}
+
+// brokerOrigin returns the origin of the bus for brokers, allocated on first use.
+func (b *Bus) brokerOrigin() broker.Origin {
+	if origin := b.origin.Load(); origin != 0 {
+		return broker.Origin(origin)
+	}
+
+	b.origin.CompareAndSwap(0, uint64(broker.NewOrigin()))
+	return broker.Origin(b.origin.Load())
+}
+
+// Forward is an identity mapper for ToBroker and FromBroker when broker messages are events.
+func Forward(e Event) (Event, bool) {
+	return e, true
+}
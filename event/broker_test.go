@@ -0,0 +1,197 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/difof/syncity/broker"
+)
+
+func TestToBroker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := NewBus()
+	b := broker.NewSync[string, Event](ctx, broker.DefaultChannel)
+	sub := b.SubscribeChannel("test")
+
+	ToBroker(ctx, bus, "test", b, "test", Forward)
+
+	if err := bus.Publish("test", testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, err := sub.Recv(ctx); err != nil || e.Topic() != "test" {
+		t.Fatalf("Expected event to be forwarded, got %v (%v)", e, err)
+	}
+}
+
+func TestFromBroker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	bus := NewBus()
+	b := broker.New[string, Event](ctx, broker.DefaultChannel)
+
+	received := make(chan Event, 1)
+	bus.Subscribe(ctx, "test", func(e Event) error {
+		received <- e
+		return nil
+	})
+
+	done := make(chan error)
+	go func() { done <- FromBroker(ctx, b, "test", bus, "test", Forward) }()
+
+	// wait until FromBroker subscribes to the broker
+	for {
+		b.PublishChannel("test", testEvent{})
+
+		select {
+		case <-received:
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("Expected message to be forwarded")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestFromBroker_HandlerError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	bus := NewBus()
+	b := broker.New[string, Event](ctx, broker.DefaultChannel)
+
+	errs := make(chan error, 16)
+	bus.SetErrorHandler(func(err error) { errs <- err })
+
+	received := make(chan Event, 16)
+	bus.Subscribe(ctx, "test", func(e Event) error {
+		received <- e
+		return errors.New("failed")
+	})
+
+	done := make(chan error)
+	go func() { done <- FromBroker(ctx, b, "test", bus, "test", Forward) }()
+
+	// keep publishing until a failed message is followed by another one
+	for n := 0; n < 2; {
+		b.PublishChannel("test", testEvent{})
+
+		select {
+		case <-received:
+			n++
+		case <-ctx.Done():
+			t.Fatalf("Expected forwarding to go on after a failed handler, received %d", n)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if err := <-errs; err == nil {
+		t.Fatal("Expected handler error to be reported")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBroker_Pair(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	bus := NewBus()
+	b := broker.New[string, Event](ctx, broker.DefaultChannel)
+
+	var handled atomic.Int64
+	var value atomic.Value
+	bus.SubscribeContext(ctx, "test", func(ctx context.Context, e Event) error {
+		if v := ctx.Value(ctxKey{}); v != nil {
+			value.Store(v)
+		}
+		handled.Add(1)
+		return nil
+	})
+
+	sub := b.SubscribeChannel("test")
+	defer sub.Close()
+
+	ToBroker(ctx, bus, "test", b, "test", Forward)
+
+	fctx := context.WithValue(ctx, ctxKey{}, "value")
+	done := make(chan error)
+	go func() { done <- FromBroker(fctx, b, "test", bus, "test", Forward) }()
+
+	if err := bus.Publish("test", testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sub.Recv(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait until FromBroker subscribes to the broker
+	published := 0
+	for handled.Load() < 2 {
+		b.PublishChannel("test", testEvent{})
+		published++
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Expected message to be forwarded")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	for i := 0; i < published; i++ {
+		if _, err := sub.Recv(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// echoes would show up as extra handler calls or broker messages
+	select {
+	case <-sub.Messages():
+		t.Fatal("Expected no echoes into the broker")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if n := handled.Load(); n != 2 {
+		t.Fatalf("Expected 2 handled events, got %d", n)
+	}
+
+	if v := value.Load(); v != "value" {
+		t.Fatalf("Expected handlers to see the context of FromBroker, got %v", v)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFromBroker_Stopped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	bctx, stop := context.WithCancel(ctx)
+	b := broker.New[string, Event](bctx, broker.DefaultChannel)
+
+	done := make(chan error)
+	go func() { done <- FromBroker(ctx, b, "test", NewBus(), "test", Forward) }()
+
+	stop()
+
+	if err := <-done; !errors.Is(err, broker.ErrStopped) {
+		t.Fatalf("Expected broker.ErrStopped, got %v", err)
+	}
+}
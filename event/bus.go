@@ -2,7 +2,10 @@ package event
 
 import (
 	"context"
+	goerrors "errors"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 
 	"github.com/difof/errors"
 )
@@ -11,66 +14,294 @@ type EventId int
 
 // Bus is a simple event bus that allows for subscribing to events and
 // publishing them. Bus is thread-safe.
+//
+// Handlers are called without holding any lock of the bus, so they are free to
+// publish and subscribe themselves. Publishing takes no lock either: it reads immutable snapshots
+// of the subscribers, so concurrent publishes never block each other nor subscribing.
+//
+// Topics are hierarchies of dot separated segments such as "user.profile.updated". Subscriptions may use
+// wildcard segments: "*" matches exactly one segment and "**" matches zero or more segments.
+// So "user.*" receives "user.created" but not "user.profile.updated", "user.**" receives both as well as
+// "user", and "**" receives every topic. Published topics should not contain wildcards.
+//
+// Overlapping subscriptions are independent: an event is delivered once to every subscription matching its
+// topic, exactly or by pattern, even if the pattern matches it in more than one way.
+// All matching handlers are called in a single order regardless of how they matched,
+// higher priority first, then in the order they subscribed.
 type Bus struct {
-	subscribers map[string]map[EventId]EventHandler
+	subscribers *registry
 	counter     EventId
 	lock        sync.Mutex
+	async       *asyncDispatcher
+	onError     func(error)
+	publishMw   []PublishMiddleware
+	publishFn   atomic.Pointer[PublishFunc]
+	handlerMw   []HandlerMiddleware
+	deliverFn   atomic.Pointer[DeliverFunc]
+	scope       atomic.Pointer[scope]
+	children    atomic.Pointer[[]*Bus]
+	closed      atomic.Bool
+	// inflight is the number of publishes, late deliveries and abandoned handlers running, see Drain.
+	inflight atomic.Int64
+	// waiters is the number of callers of wait, end only wakes them when there are any.
+	waiters  atomic.Int32
+	idleLock sync.Mutex
+	idle     sync.Cond
+	// origin identifies the bus to brokers, see ToBroker.
+	origin atomic.Uint64
 }
 
 // NewBus creates a new event bus.
-func NewBus() *Bus {
-	return &Bus{
-		subscribers: make(map[string]map[EventId]EventHandler),
+func NewBus() (b *Bus) {
+	b = &Bus{subscribers: newRegistry()}
+	b.idle.L = &b.idleLock
+
+	b.setPublish(b.publishBase)
+	b.setDeliver(deliver)
+
+	return
+}
+
+// NewAsyncBus creates a new event bus which delivers events asynchronously on a pool of workers,
+// until ctx is done.
+//
+// Publish queues the event and returns right away, blocking only while the queue is full.
+// Events of the same topic are delivered in the order they were published.
+// Handler errors are passed to the function set by SetErrorHandler.
+//
+// Handlers publishing into a full queue of their own worker block forever,
+// so size the queues for the events published by handlers.
+func NewAsyncBus(ctx context.Context, workers, queueSize int) (b *Bus) {
+	b = NewBus()
+	b.async = newAsyncDispatcher(ctx, workers, queueSize)
+	b.async.start(func(ctx context.Context, topic string, event Event) {
+		if err := b.dispatch(ctx, topic, event); err != nil {
+			b.handleError(err)
+		}
+	})
+
+	return
+}
+
+// SetErrorHandler sets the function called with errors of handlers called asynchronously.
+func (b *Bus) SetErrorHandler(f func(error)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.onError = f
+}
+
+func (b *Bus) handleError(err error) {
+	b.lock.Lock()
+	f := b.onError
+	b.lock.Unlock()
+
+	if f != nil {
+		f(err)
 	}
 }
 
 // Subscribe adds a new event handler to the bus for a given topic.
 // removes subscription when context is done before receiving event
-func (b *Bus) Subscribe(ctx context.Context, topic string, handler EventHandler) (id EventId) {
+//
+// Handlers are called in the order they subscribed, use On to configure the subscription.
+func (b *Bus) Subscribe(ctx context.Context, topic string, handler EventHandler) *Subscription {
+	return b.On(topic).Do(ctx, handler)
+}
+
+// SubscribeContext is same as Subscribe with a handler receiving the context of the publisher.
+func (b *Bus) SubscribeContext(ctx context.Context, topic string, handler ContextHandler) *Subscription {
+	return b.On(topic).DoContext(ctx, handler)
+}
+
+// Once subscribes the handler to receive a single event, see Subscribe.
+func (b *Bus) Once(ctx context.Context, topic string, handler EventHandler) *Subscription {
+	return b.On(topic).Times(1).Do(ctx, handler)
+}
+
+// SubscribeN subscribes the handler to receive n events, see Subscribe.
+// The subscription is removed right away if n <= 0.
+func (b *Bus) SubscribeN(ctx context.Context, topic string, n int, handler EventHandler) *Subscription {
+	return b.On(topic).Times(n).Do(ctx, handler)
+}
+
+// subscribe assigns an id to the subscriber and adds it to the bus.
+func (b *Bus) subscribe(s *subscriber) EventId {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
-	if b.subscribers[topic] == nil {
-		b.subscribers[topic] = make(map[EventId]EventHandler)
-	}
-
-	id = b.counter
-	b.subscribers[topic][b.counter] = handler
+	s.id = b.counter
 	b.counter++
+	b.subscribers.add(s)
 
-	go func(topic string, id EventId, handler EventHandler) {
-		<-ctx.Done()
-		b.Unsubscribe(topic, id, handler)
-	}(topic, id, handler)
+	return s.id
+}
 
-	return
+// unsubscribe removes the subscriber from the bus.
+func (b *Bus) unsubscribe(s *subscriber) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.subscribers.remove(s.key, s.id)
 }
 
 // Unsubscribe removes an event handler from the bus for a given topic.
+//
+// Deprecated: Use Subscription.Unsubscribe. The handler is ignored, the subscription is found by topic and id.
 func (b *Bus) Unsubscribe(topic string, id EventId, handler EventHandler) {
-	b.lock.Lock()
-	defer b.lock.Unlock()
+	for _, s := range b.subscribers.all() {
+		if s.key == topic && s.id == id {
+			s.handle.Unsubscribe()
+			return
+		}
+	}
+}
 
-	if b.subscribers[topic] == nil {
+// PublishContext publishes an event to all subscribers of a given topic, passing ctx to their handlers.
+// See Publish.
+func (b *Bus) PublishContext(ctx context.Context, topic string, event Event) (err error) {
+	if event == nil {
 		return
 	}
 
-	delete(b.subscribers[topic], id)
+	return b.publish(unmarked(ctx), topic, event)
 }
 
 // Publish publishes an event to all subscribers of a given topic.
+// Every subscriber receives the event even if others fail, the failures are
+// returned as a *PublishError.
+// For async buses the event is queued, see NewAsyncBus.
 func (b *Bus) Publish(topic string, event Event) (err error) {
 	if event == nil {
 		return
 	}
 
-	b.lock.Lock()
-	defer b.lock.Unlock()
-	defer errors.Recover(&err)
+	return b.publish(context.Background(), topic, event)
+}
 
-	for i, handler := range b.subscribers[topic] {
-		errors.Mustf(handler(event))("handler %d failed", i)
+// publish publishes the event through the publish middleware, then forwards it to scoped buses.
+// Returns ErrClosed if the bus is closed.
+func (b *Bus) publish(ctx context.Context, topic string, event Event) error {
+	// counted before checking closed, so Close either sees the publish or the publish sees Close
+	b.begin()
+	defer b.end()
+
+	if b.closed.Load() {
+		return ErrClosed
 	}
 
-	return
+	err := (*b.publishFn.Load())(ctx, topic, event)
+	if perr := b.propagate(ctx, topic, event); perr != nil {
+		return goerrors.Join(err, perr)
+	}
+
+	return err
+}
+
+// publishBase queues the event for async buses, otherwise dispatches it right away.
+// Requests of Ask and entries of outboxes are always dispatched right away.
+func (b *Bus) publishBase(ctx context.Context, topic string, event Event) error {
+	if b.async != nil && queryFrom(ctx) == nil && !isSync(ctx) {
+		return b.async.enqueue(ctx, topic, event)
+	}
+
+	return b.dispatch(ctx, topic, event)
+}
+
+// syncKey marks the context of publishing an event which bypasses the queue of async buses.
+type syncKey struct{}
+
+// withSync returns ctx marked to publish synchronously.
+func withSync(ctx context.Context) context.Context {
+	return context.WithValue(ctx, syncKey{}, true)
+}
+
+// isSync reports whether ctx is marked to publish synchronously.
+func isSync(ctx context.Context) bool {
+	sync, _ := ctx.Value(syncKey{}).(bool)
+	return sync
+}
+
+// unmarked returns ctx without the marks of Ask and outboxes. Handlers pass their context on when
+// publishing, so public publishes drop the marks to not be taken for the request or entry being handled.
+func unmarked(ctx context.Context) context.Context {
+	if queryFrom(ctx) == nil && !isSync(ctx) {
+		return ctx
+	}
+
+	return context.WithValue(context.WithValue(ctx, queryKey{}, (*query)(nil)), syncKey{}, false)
+}
+
+// Flush waits until all queued events of an async bus are handled or ctx is done.
+// Returns right away for synchronous buses.
+func (b *Bus) Flush(ctx context.Context) error {
+	if b.async == nil {
+		return nil
+	}
+
+	return b.async.flush(ctx)
+}
+
+// dispatch calls the handlers of the topic and of the type of the event in order, regardless of others failing.
+// Returns a *PublishError if any of the handlers failed.
+//
+// A handler returning ErrStopPropagation, or cancelling a Cancellable event, stops the remaining handlers.
+func (b *Bus) dispatch(ctx context.Context, topic string, event Event) error {
+	subs := b.subscribers.match(topic, typeKey(event))
+	deliver := *b.deliverFn.Load()
+	ctx = context.WithValue(ctx, topicKey{}, topic)
+
+	cancellable, _ := event.(Cancellable)
+
+	var errs []*HandlerError
+	var cancelled *CancelledError
+	for _, s := range subs {
+		d := Delivery{Topic: topic, Event: event, Subscriber: s.id, sub: s}
+		if s.pacer != nil && !s.pacer.admit(ctx, d) {
+			continue
+		}
+
+		if !s.acquire() {
+			continue
+		}
+
+		herr := b.deliverRetry(ctx, deliver, d)
+		if herr != nil && errors.Is(herr.Err, ErrStopPropagation) {
+			// joined with another error, the failure is reported as well
+			if herr.Err != ErrStopPropagation {
+				errs = append(errs, herr)
+			}
+			break
+		}
+
+		if herr != nil {
+			errs = append(errs, herr)
+		}
+
+		if cancellable != nil && cancellable.Cancelled() {
+			cancelled = &CancelledError{Topic: topic, Id: s.id, Reason: cancellable.Reason()}
+			break
+		}
+	}
+
+	return newPublishError(topic, errs, cancelled)
+}
+
+type topicKey struct{}
+
+// TopicFrom returns the topic the event being handled was published on, which differs from the topic
+// of the subscription for wildcard subscriptions.
+func TopicFrom(ctx context.Context) (string, bool) {
+	topic, ok := ctx.Value(topicKey{}).(string)
+	return topic, ok
+}
+
+// callHandler delivers the event through the handler middleware, recovering from their panics.
+func callHandler(ctx context.Context, deliver DeliverFunc, d Delivery) (herr *HandlerError) {
+	defer func() {
+		if r := recover(); r != nil {
+			herr = newHandlerError(d, &PanicError{Value: r, Stack: debug.Stack()})
+		}
+	}()
+
+	return newHandlerError(d, deliver(ctx, d))
 }
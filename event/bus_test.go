@@ -2,6 +2,7 @@ package event
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,28 +17,141 @@ func (e testEvent) Topic() string {
 
 func TestBus(t *testing.T) {
 	b := NewBus()
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
-	b.Subscribe(ctx, "test", func(e Event) (err error) {
-		defer errors.Recover(&err)
+	ctx := context.Background()
 
-		t.Logf("1 received event %s", e.Topic())
+	var received []int
+	b.Subscribe(ctx, "test", func(e Event) error {
+		received = append(received, 1)
+		return nil
+	})
 
-		return
+	b.Subscribe(ctx, "test", func(e Event) error {
+		received = append(received, 2)
+		return nil
 	})
 
-	b.Subscribe(ctx, "test", func(e Event) (err error) {
-		defer errors.Recover(&err)
+	if err := b.Publish("test", &testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 2 || received[0] != 1 || received[1] != 2 {
+		t.Fatalf("Expected both handlers to receive the event in order, got %v", received)
+	}
+}
+
+type numberEvent struct {
+	topic string
+	n     int
+}
+
+func (e numberEvent) Topic() string {
+	return e.topic
+}
 
-		t.Logf("2 received event %s", e.Topic())
+func TestBus_PublishFromHandler(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
 
-		return
+	received := false
+	b.Subscribe(ctx, "first", func(e Event) error {
+		return b.Publish("second", e)
+	})
+	b.Subscribe(ctx, "second", func(e Event) error {
+		received = true
+		return nil
 	})
 
-	if err := b.Publish("test", &testEvent{}); err != nil {
+	if err := b.Publish("first", &testEvent{}); err != nil {
 		t.Fatal(err)
 	}
 
-	<-ctx.Done()
+	if !received {
+		t.Fatal("Expected event published by handler to be received")
+	}
+}
+
+func TestAsyncBus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	b := NewAsyncBus(ctx, 4, 8)
+	topics := []string{"a", "b", "c"}
+	received := map[string][]int{}
+	var lock sync.Mutex
+
+	for _, topic := range topics {
+		b.Subscribe(ctx, topic, func(e Event) error {
+			lock.Lock()
+			defer lock.Unlock()
+			ne := e.(numberEvent)
+			received[ne.topic] = append(received[ne.topic], ne.n)
+			return nil
+		})
+	}
+
+	const count = 100
+	for i := 0; i < count; i++ {
+		for _, topic := range topics {
+			if err := b.Publish(topic, numberEvent{topic: topic, n: i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := b.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, topic := range topics {
+		if len(received[topic]) != count {
+			t.Fatalf("Expected %d events on %s, got %d", count, topic, len(received[topic]))
+		}
+
+		for i, n := range received[topic] {
+			if n != i {
+				t.Fatalf("Expected events of %s in order, got %d at %d", topic, n, i)
+			}
+		}
+	}
+}
+
+func TestBus_PublishError(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	called := 0
+	failing := b.Subscribe(ctx, "test", func(e Event) error {
+		called++
+		return errFailed
+	})
+	panicking := b.Subscribe(ctx, "test", func(e Event) error {
+		called++
+		panic("boom")
+	})
+	b.Subscribe(ctx, "test", func(e Event) error {
+		called++
+		return nil
+	})
+
+	err := b.Publish("test", &testEvent{})
+	if called != 3 {
+		t.Fatalf("Expected all 3 handlers to be called, got %d", called)
+	}
+
+	var perr *PublishError
+	if !errors.As(err, &perr) || len(perr.Errors) != 2 {
+		t.Fatalf("Expected PublishError with 2 errors, got %v", err)
+	}
+
+	if perr.Errors[0].Id != failing.Id() || !errors.Is(err, errFailed) {
+		t.Fatalf("Expected handler %d to fail with %v, got %v", failing.Id(), errFailed, perr.Errors[0])
+	}
+
+	if perr.Errors[1].Id != panicking.Id() || perr.Errors[1].Panic != "boom" || len(perr.Errors[1].Stack) == 0 {
+		t.Fatalf("Expected handler %d to panic with stack, got %v", panicking.Id(), perr.Errors[1])
+	}
 }
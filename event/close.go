@@ -0,0 +1,89 @@
+package event
+
+import (
+	"context"
+)
+
+// begin counts a running publish, late delivery or abandoned handler, see Drain.
+func (b *Bus) begin() {
+	b.inflight.Add(1)
+}
+
+// end uncounts what begin counted, waking the callers of wait once nothing runs.
+func (b *Bus) end() {
+	if b.inflight.Add(-1) == 0 && b.waiters.Load() > 0 {
+		b.idleLock.Lock()
+		b.idle.Broadcast()
+		b.idleLock.Unlock()
+	}
+}
+
+// wait blocks until nothing counted by begin runs or ctx is done.
+func (b *Bus) wait(ctx context.Context) error {
+	b.idleLock.Lock()
+	defer b.idleLock.Unlock()
+
+	// registered before reading inflight, so end either sees the waiter or the waiter sees end
+	b.waiters.Add(1)
+	defer b.waiters.Add(-1)
+
+	stop := context.AfterFunc(ctx, func() {
+		b.idleLock.Lock()
+		b.idle.Broadcast()
+		b.idleLock.Unlock()
+	})
+	defer stop()
+
+	for b.inflight.Load() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		b.idle.Wait()
+	}
+
+	return nil
+}
+
+// Drain waits until the running publishes, and the queued events of async buses, are handled
+// or ctx is done. Events held back by Debounce and Coalesce are delivered right away, and handlers
+// abandoned after their timeout are waited for as well.
+// Unlike Close, new events are still accepted, so Drain of a busy bus may never return.
+func (b *Bus) Drain(ctx context.Context) error {
+	if err := b.wait(ctx); err != nil {
+		return err
+	}
+
+	if err := b.Flush(ctx); err != nil {
+		return err
+	}
+
+	for _, s := range b.subscribers.all() {
+		if s.pacer != nil {
+			s.pacer.flush()
+		}
+	}
+
+	return b.wait(ctx)
+}
+
+// Close shuts the bus down gracefully: new publishes fail with ErrClosed, then the bus is drained
+// until ctx is done, see Drain. Then all subscriptions are removed and the workers of async buses are stopped.
+// Returns the error of ctx if it is done before the events are handled, the remaining events are dropped.
+//
+// Subscribing to a closed bus returns a removed subscription. Closing a closed bus returns ErrClosed.
+// Handlers must not call Close or Drain, they would wait for themselves.
+func (b *Bus) Close(ctx context.Context) error {
+	if b.closed.Swap(true) {
+		return ErrClosed
+	}
+
+	err := b.Drain(ctx)
+
+	b.Teardown()
+	if b.async != nil {
+		b.async.stop()
+	}
+
+	return err
+}
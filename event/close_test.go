@@ -0,0 +1,185 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBus_Close(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var handled atomic.Bool
+	sub := b.Subscribe(ctx, "slow", func(e Event) error {
+		close(started)
+		<-release
+		handled.Store(true)
+		return nil
+	})
+
+	published := make(chan error, 1)
+	go func() { published <- b.Publish("slow", numberEvent{topic: "slow"}) }()
+	<-started
+
+	closed := make(chan error, 1)
+	go func() { closed <- b.Close(ctx) }()
+
+	// wait for Close to reject new publishes
+	for !b.closed.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := b.Publish("slow", numberEvent{topic: "slow"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed, got %v", err)
+	}
+
+	select {
+	case <-closed:
+		t.Fatal("Expected Close to wait for the running handler")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-published; err != nil || !handled.Load() {
+		t.Fatalf("Expected in-flight publish to finish, got %v", err)
+	}
+
+	select {
+	case <-sub.Done():
+	default:
+		t.Fatal("Expected Close to remove all subscriptions")
+	}
+
+	late := b.Subscribe(ctx, "slow", func(e Event) error { return nil })
+	select {
+	case <-late.Done():
+	default:
+		t.Fatal("Expected subscription of a closed bus to be removed")
+	}
+
+	if err := b.Close(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected second Close to return ErrClosed, got %v", err)
+	}
+}
+
+func TestAsyncBus_Close(t *testing.T) {
+	b := NewAsyncBus(context.Background(), 1, 16)
+
+	var handled atomic.Int64
+	b.Subscribe(context.Background(), "queued", func(e Event) error {
+		time.Sleep(time.Millisecond)
+		handled.Add(1)
+		return nil
+	})
+
+	for i := 0; i < 10; i++ {
+		if err := b.Publish("queued", numberEvent{topic: "queued", n: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := b.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := handled.Load(); n != 10 {
+		t.Fatalf("Expected Close to wait for all queued events, handled %d", n)
+	}
+}
+
+func TestBus_ClosePaced(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	var debounced atomic.Int64
+	b.On("debounced").Debounce(time.Hour).Do(ctx, func(e Event) error {
+		debounced.Store(int64(e.(numberEvent).n))
+		return nil
+	})
+
+	var batch atomic.Int64
+	b.On("coalesced").Coalesce(time.Hour, nil).DoBatch(ctx, func(ctx context.Context, events []Event) error {
+		batch.Store(int64(len(events)))
+		return nil
+	})
+
+	for i := 1; i <= 3; i++ {
+		b.Publish("debounced", numberEvent{topic: "debounced", n: i})
+		b.Publish("coalesced", numberEvent{topic: "coalesced", n: i})
+	}
+
+	if err := b.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := debounced.Load(); n != 3 {
+		t.Fatalf("Expected Close to deliver the debounced event 3, got %d", n)
+	}
+
+	if n := batch.Load(); n != 3 {
+		t.Fatalf("Expected Close to deliver the batch of 3 events, got %d", n)
+	}
+}
+
+func TestBus_CloseAbandoned(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	release := make(chan struct{})
+	var handled atomic.Bool
+	b.On("slow").Timeout(time.Millisecond).Do(ctx, func(e Event) error {
+		<-release
+		handled.Store(true)
+		return nil
+	})
+
+	var terr *TimeoutError
+	if err := b.Publish("slow", numberEvent{topic: "slow"}); !errors.As(err, &terr) {
+		t.Fatalf("Expected *TimeoutError, got %v", err)
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- b.Close(ctx) }()
+
+	select {
+	case <-closed:
+		t.Fatal("Expected Close to wait for the abandoned handler")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+
+	if err := <-closed; err != nil || !handled.Load() {
+		t.Fatalf("Expected abandoned handler to finish, got %v", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	stuck := NewBus()
+	stuck.On("stuck").Timeout(time.Millisecond).Do(ctx, func(e Event) error {
+		<-done
+		return nil
+	})
+	stuck.Publish("stuck", numberEvent{topic: "stuck"})
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	if err := stuck.Close(tctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
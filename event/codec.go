@@ -0,0 +1,100 @@
+package event
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/difof/errors"
+)
+
+// Codec encodes events to bytes and decodes them back.
+// The topic passed to Decode is the one the event was published on, which may differ from the
+// topic of the event, so codecs should identify the type of the event from data.
+type Codec interface {
+	Encode(event Event) ([]byte, error)
+	Decode(topic string, data []byte) (Event, error)
+}
+
+// jsonEnvelope is how JSONCodec encodes events.
+type jsonEnvelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// JSONCodec encodes events as JSON along with the topic of the event as their type, and decodes them
+// into the types registered for their topics regardless of the topic they were published on.
+// Bare payloads without the type are decoded into the type registered for the topic passed to Decode.
+type JSONCodec struct {
+	types map[string]reflect.Type
+	lock  sync.RWMutex
+}
+
+// NewJSONCodec creates a new JSONCodec with the types of the given events registered.
+func NewJSONCodec(events ...Event) (c *JSONCodec) {
+	c = &JSONCodec{types: make(map[string]reflect.Type)}
+
+	for _, event := range events {
+		c.Register(event)
+	}
+
+	return
+}
+
+// Register registers the type of the event as the type of its topic.
+// Pointer events are decoded into pointers.
+func (c *JSONCodec) Register(event Event) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.types[event.Topic()] = reflect.TypeOf(event)
+}
+
+func (c *JSONCodec) Encode(event Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode event of %s", event.Topic())
+	}
+
+	return json.Marshal(jsonEnvelope{Type: event.Topic(), Data: data})
+}
+
+func (c *JSONCodec) Decode(topic string, data []byte) (Event, error) {
+	var env jsonEnvelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Type == "" || env.Data == nil {
+		env = jsonEnvelope{Type: topic, Data: data}
+	}
+
+	c.lock.RLock()
+	t, ok := c.types[env.Type]
+	c.lock.RUnlock()
+
+	if !ok {
+		return nil, errors.Newf("no event type registered for topic %s", env.Type)
+	}
+
+	return decodeJSON(t, env.Data)
+}
+
+// decodeJSON decodes data into a new value of type t.
+func decodeJSON(t reflect.Type, data []byte) (Event, error) {
+	ptr := t.Kind() == reflect.Pointer
+	if ptr {
+		t = t.Elem()
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s", t)
+	}
+
+	if !ptr {
+		v = v.Elem()
+	}
+
+	event, ok := v.Interface().(Event)
+	if !ok {
+		return nil, errors.Newf("%s is not an event", v.Type())
+	}
+
+	return event, nil
+}
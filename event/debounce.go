@@ -0,0 +1,236 @@
+package event
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BatchHandler handles a batch of coalesced events.
+type BatchHandler func(ctx context.Context, events []Event) error
+
+// pacer holds back deliveries of a subscriber, see SubscribeConfig.Debounce, Throttle and Coalesce.
+type pacer interface {
+	// admit reports whether the delivery is to be made right away.
+	// Deliveries held back are made later by the pacer through the bus, or dropped.
+	admit(ctx context.Context, d Delivery) bool
+	// flush makes the held back deliveries right away, see Bus.Drain.
+	flush()
+}
+
+// debouncer delivers the last event of a burst once no event arrived for delay, for each topic on its own.
+type debouncer struct {
+	bus   *Bus
+	delay time.Duration
+
+	pending map[string]*debounced
+	lock    sync.Mutex
+}
+
+// debounced is the delivery held back by a debouncer for a topic.
+type debounced struct {
+	timer *time.Timer
+	ctx   context.Context
+	d     Delivery
+}
+
+func (p *debouncer) admit(ctx context.Context, d Delivery) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if e, ok := p.pending[d.Topic]; ok {
+		e.ctx, e.d = context.WithoutCancel(ctx), d
+		e.timer.Reset(p.delay)
+		return false
+	}
+
+	if p.pending == nil {
+		p.pending = make(map[string]*debounced)
+	}
+
+	e := &debounced{ctx: context.WithoutCancel(ctx), d: d}
+	e.timer = time.AfterFunc(p.delay, func() { p.fire(e) })
+	p.pending[d.Topic] = e
+
+	return false
+}
+
+// fire is called by the timer of e, counted by the bus so Drain waits for the delivery.
+func (p *debouncer) fire(e *debounced) {
+	p.bus.begin()
+	defer p.bus.end()
+
+	p.lock.Lock()
+	// e was flushed, or replaced after being flushed
+	if p.pending[e.d.Topic] != e {
+		p.lock.Unlock()
+		return
+	}
+	delete(p.pending, e.d.Topic)
+	ctx, d := e.ctx, e.d
+	p.lock.Unlock()
+
+	p.bus.deliverLate(ctx, d)
+}
+
+func (p *debouncer) flush() {
+	p.lock.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.lock.Unlock()
+
+	for _, topic := range sortedKeys(pending) {
+		e := pending[topic]
+		e.timer.Stop()
+		p.bus.deliverLate(e.ctx, e.d)
+	}
+}
+
+// throttler delivers at most one event per interval, dropping the others.
+type throttler struct {
+	interval time.Duration
+	last     time.Time
+	lock     sync.Mutex
+}
+
+func (p *throttler) admit(context.Context, Delivery) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	if !p.last.IsZero() && now.Sub(p.last) < p.interval {
+		return false
+	}
+
+	p.last = now
+	return true
+}
+
+func (p *throttler) flush() {}
+
+// coalescer collects the events of a window into a batch, keeping the last event of each key,
+// for each topic on its own.
+type coalescer struct {
+	bus     *Bus
+	handler BatchHandler
+	window  time.Duration
+	key     func(Event) string
+
+	batches map[string]*batch
+	lock    sync.Mutex
+}
+
+// batch is the batch of a topic collected by a coalescer.
+type batch struct {
+	timer  *time.Timer
+	ctx    context.Context
+	last   Delivery
+	events []Event
+	keys   map[string]int
+}
+
+// handle is the handler of the subscriber, delivering single events as batches when there is no window.
+func (p *coalescer) handle(ctx context.Context, e Event) error {
+	return p.handler(ctx, []Event{e})
+}
+
+func (p *coalescer) admit(ctx context.Context, d Delivery) bool {
+	if p.window <= 0 {
+		return true
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	b, ok := p.batches[d.Topic]
+	if !ok {
+		if p.batches == nil {
+			p.batches = make(map[string]*batch)
+		}
+
+		b = &batch{keys: make(map[string]int)}
+		b.timer = time.AfterFunc(p.window, func() { p.fire(b) })
+		p.batches[d.Topic] = b
+	}
+
+	b.ctx, b.last = context.WithoutCancel(ctx), d
+	if p.key == nil {
+		b.events = append(b.events, d.Event)
+		return false
+	}
+
+	k := p.key(d.Event)
+	if i, ok := b.keys[k]; ok {
+		b.events[i] = d.Event
+		return false
+	}
+
+	b.keys[k] = len(b.events)
+	b.events = append(b.events, d.Event)
+
+	return false
+}
+
+// fire is called by the timer of b at the end of the window, counted by the bus so Drain waits for the delivery.
+func (p *coalescer) fire(b *batch) {
+	p.bus.begin()
+	defer p.bus.end()
+
+	p.lock.Lock()
+	// b was flushed already
+	if p.batches[b.last.Topic] != b {
+		p.lock.Unlock()
+		return
+	}
+	delete(p.batches, b.last.Topic)
+	p.lock.Unlock()
+
+	p.deliver(b)
+}
+
+func (p *coalescer) flush() {
+	p.lock.Lock()
+	batches := p.batches
+	p.batches = nil
+	p.lock.Unlock()
+
+	for _, topic := range sortedKeys(batches) {
+		b := batches[topic]
+		b.timer.Stop()
+		p.deliver(b)
+	}
+}
+
+// deliver delivers the batch taken out of the coalescer.
+func (p *coalescer) deliver(b *batch) {
+	events, d := slices.Clip(b.events), b.last
+	d.handler = func(ctx context.Context, _ Event) error {
+		return p.handler(ctx, events)
+	}
+
+	p.bus.deliverLate(b.ctx, d)
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+	return keys
+}
+
+// deliverLate makes a delivery held back by a pacer through the handler middleware, reporting its failure
+// to the error handler of the bus since the publish has already returned.
+func (b *Bus) deliverLate(ctx context.Context, d Delivery) {
+	if !d.sub.acquire() {
+		return
+	}
+
+	if herr := b.deliverRetry(ctx, *b.deliverFn.Load(), d); herr != nil && herr.Err != ErrStopPropagation {
+		b.handleError(newPublishError(d.Topic, []*HandlerError{herr}, nil))
+	}
+}
@@ -0,0 +1,205 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscribeConfig_Debounce(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	got := make(chan int, 10)
+	b.On("config.changed").Debounce(20*time.Millisecond).Do(ctx, func(e Event) error {
+		got <- e.(numberEvent).n
+		return nil
+	})
+
+	for i := 1; i <= 5; i++ {
+		if err := b.Publish("config.changed", numberEvent{topic: "config.changed", n: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case n := <-got:
+		if n != 5 {
+			t.Fatalf("Expected the last event, got %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected debounced event to be delivered")
+	}
+
+	select {
+	case n := <-got:
+		t.Fatalf("Expected a single delivery, got %d", n)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscribeConfig_Throttle(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	var got []int
+	b.On("config.changed").Throttle(time.Hour).Do(ctx, func(e Event) error {
+		got = append(got, e.(numberEvent).n)
+		return nil
+	})
+
+	for i := 1; i <= 5; i++ {
+		if err := b.Publish("config.changed", numberEvent{topic: "config.changed", n: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(got) != 1 || got[0] != 1 {
+		t.Fatalf("Expected only the first event, got %v", got)
+	}
+}
+
+type stockEvent struct {
+	sku string
+	qty int
+}
+
+func (e *stockEvent) Topic() string {
+	return "stock.changed"
+}
+
+func TestSubscribeConfig_Coalesce(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	failure := errors.New("batch failed")
+	errs := make(chan error, 1)
+	b.SetErrorHandler(func(err error) { errs <- err })
+
+	var lock sync.Mutex
+	var batch []Event
+	key := func(e Event) string { return e.(*stockEvent).sku }
+	b.On("stock.changed").Coalesce(20*time.Millisecond, key).DoBatch(ctx, func(ctx context.Context, events []Event) error {
+		lock.Lock()
+		defer lock.Unlock()
+		batch = events
+		return failure
+	})
+
+	for i, sku := range []string{"a", "b", "a"} {
+		if err := b.Publish("stock.changed", &stockEvent{sku: sku, qty: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, failure) {
+			t.Fatalf("Expected batch error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected coalesced batch to be delivered")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(batch) != 2 || batch[0].(*stockEvent).qty != 2 || key(batch[1]) != "b" {
+		t.Fatalf("Expected one event per key, got %v", batch)
+	}
+}
+
+func TestSubscribeConfig_DebounceMiddleware(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	var delivered atomic.Int64
+	b.UseHandler(func(next DeliverFunc) DeliverFunc {
+		return func(ctx context.Context, d Delivery) error {
+			delivered.Add(1)
+			return next(ctx, d)
+		}
+	})
+
+	errs := make(chan error, 4)
+	b.SetErrorHandler(func(err error) { errs <- err })
+
+	var calls atomic.Int64
+	b.On("config.changed").Timeout(5*time.Millisecond).Retry(RetryPolicy{MaxAttempts: 2}).DeadLetter("").
+		Debounce(10*time.Millisecond).DoContext(ctx, func(ctx context.Context, e Event) error {
+		calls.Add(1)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := b.Publish("config.changed", numberEvent{topic: "config.changed", n: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if delivered.Load() != 0 {
+		t.Fatal("Expected held back events not to reach the middleware")
+	}
+
+	select {
+	case err := <-errs:
+		var terr *TimeoutError
+		if !errors.As(err, &terr) {
+			t.Fatalf("Expected a timeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected debounced handler to time out")
+	}
+
+	if calls.Load() != 2 || delivered.Load() != 2 {
+		t.Fatalf("Expected 2 attempts through the middleware, got %d calls and %d deliveries", calls.Load(), delivered.Load())
+	}
+}
+
+func TestSubscribeConfig_PerTopic(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	var lock sync.Mutex
+	debounced := map[string]int{}
+	b.On("config.*").Debounce(time.Hour).DoContext(ctx, func(ctx context.Context, e Event) error {
+		topic, _ := TopicFrom(ctx)
+		lock.Lock()
+		debounced[topic] = e.(numberEvent).n
+		lock.Unlock()
+		return nil
+	})
+
+	batches := map[string]int{}
+	b.On("metric.*").Coalesce(time.Hour, nil).DoBatch(ctx, func(ctx context.Context, events []Event) error {
+		topic, _ := TopicFrom(ctx)
+		lock.Lock()
+		batches[topic] = len(events)
+		lock.Unlock()
+		return nil
+	})
+
+	for i := 1; i <= 3; i++ {
+		for _, topic := range []string{"config.a", "config.b", "metric.a"} {
+			if err := b.Publish(topic, numberEvent{topic: topic, n: i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	_ = b.Publish("metric.b", numberEvent{topic: "metric.b"})
+
+	if err := b.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(debounced) != 2 || debounced["config.a"] != 3 || debounced["config.b"] != 3 {
+		t.Fatalf("Expected the last event of each topic, got %v", debounced)
+	}
+
+	if len(batches) != 2 || batches["metric.a"] != 3 || batches["metric.b"] != 1 {
+		t.Fatalf("Expected a batch for each topic, got %v", batches)
+	}
+}
@@ -0,0 +1,176 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrStopPropagation can be returned by a handler to skip the remaining handlers of the event.
+	// It is not reported as a failure, unless joined with another error using errors.Join.
+	ErrStopPropagation = errors.New("event: stop propagation")
+
+	// ErrCancelled matches the error of publishing a Cancellable event which got cancelled by a handler.
+	ErrCancelled = errors.New("event: cancelled")
+
+	// ErrNoResult is returned by responders to decline a request, and by Ask when no responder answered.
+	ErrNoResult = errors.New("event: no result")
+
+	// ErrClosed is returned by publishing on a closed bus.
+	ErrClosed = errors.New("event: bus closed")
+
+	// ErrSagaTimeout matches the failure of a saga instance whose step timed out.
+	ErrSagaTimeout = errors.New("event: saga step timed out")
+)
+
+// HandlerError is the error of a single failed handler.
+type HandlerError struct {
+	Id    EventId
+	Topic string
+	Err   error
+	// Panic is the recovered value if the handler panicked, Stack is the stack trace of the panic.
+	Panic any
+	Stack []byte
+}
+
+// newHandlerError returns the HandlerError of the delivery if err is not nil.
+func newHandlerError(d Delivery, err error) *HandlerError {
+	if err == nil {
+		return nil
+	}
+
+	herr := &HandlerError{Id: d.Subscriber, Topic: d.Topic, Err: err}
+
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		herr.Panic = perr.Value
+		herr.Stack = perr.Stack
+	}
+
+	return herr
+}
+
+func (e *HandlerError) Error() string {
+	if e.Panic != nil {
+		return fmt.Sprintf("handler %d of %s panicked: %v\n%s", e.Id, e.Topic, e.Panic, e.Stack)
+	}
+
+	return fmt.Sprintf("handler %d of %s failed: %v", e.Id, e.Topic, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error { return e.Err }
+
+// PanicError is a panic recovered from a handler.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// PublishError aggregates the errors of all handlers failed on a single publish.
+// Use errors.As to get the PublishError or any of the HandlerError.
+type PublishError struct {
+	Topic  string
+	Errors []*HandlerError
+}
+
+func (e *PublishError) Error() string {
+	msgs := make([]string, 0, len(e.Errors)+1)
+	msgs = append(msgs, fmt.Sprintf("publish %s: %d handlers failed", e.Topic, len(e.Errors)))
+
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (e *PublishError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// TimeoutError is the error of a handler abandoned after exceeding its timeout.
+// Err is context.DeadlineExceeded or the error of the cancelled publish context.
+type TimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("handler abandoned after %s: %v", e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// OutboxError is the failure of delivering an entry of an outbox, see OutboxDispatcher.
+type OutboxError struct {
+	Entry OutboxEntry
+	// Attempts is the number of rounds the entry failed in a row.
+	Attempts int
+	// Parked is set once the entry is given up on, see OutboxDispatcher.MaxAttempts.
+	Parked bool
+	Err    error
+}
+
+func (e *OutboxError) Error() string {
+	if e.Parked {
+		return fmt.Sprintf("outbox entry %d parked after %d attempts: %v", e.Entry.Id, e.Attempts, e.Err)
+	}
+
+	return fmt.Sprintf("failed to deliver outbox entry %d, attempt %d: %v", e.Entry.Id, e.Attempts, e.Err)
+}
+
+func (e *OutboxError) Unwrap() error { return e.Err }
+
+// CancelledError is returned when a handler cancels a Cancellable event.
+type CancelledError struct {
+	Topic  string
+	Id     EventId
+	Reason error
+}
+
+func (e *CancelledError) Error() string {
+	if e.Reason == nil {
+		return fmt.Sprintf("event %s cancelled by handler %d", e.Topic, e.Id)
+	}
+
+	return fmt.Sprintf("event %s cancelled by handler %d: %v", e.Topic, e.Id, e.Reason)
+}
+
+func (e *CancelledError) Is(target error) bool { return target == ErrCancelled }
+
+func (e *CancelledError) Unwrap() error { return e.Reason }
+
+// newPublishError builds the error of a publish from the failed handlers and the cancellation if any.
+func newPublishError(topic string, errs []*HandlerError, cancelled *CancelledError) error {
+	var err error
+	if len(errs) > 0 {
+		err = &PublishError{Topic: topic, Errors: errs}
+	}
+
+	if cancelled != nil {
+		if err == nil {
+			return cancelled
+		}
+
+		return errors.Join(cancelled, err)
+	}
+
+	return err
+}
@@ -1,7 +1,38 @@
 package event
 
+import "context"
+
 type Event interface {
 	Topic() string
 }
 
+// Cancellable is an event which handlers can veto. Once cancelled, the remaining handlers are not called.
+type Cancellable interface {
+	Event
+	Cancel(reason error)
+	Cancelled() bool
+	Reason() error
+}
+
+// CancellableEvent implements Cancellable, embed it into events published by pointer.
+type CancellableEvent struct {
+	cancelled bool
+	reason    error
+}
+
+// Cancel vetoes the event with an optional reason.
+func (e *CancellableEvent) Cancel(reason error) {
+	e.cancelled = true
+	e.reason = reason
+}
+
+// Cancelled reports whether the event is cancelled.
+func (e *CancellableEvent) Cancelled() bool { return e.cancelled }
+
+// Reason returns the reason the event is cancelled with.
+func (e *CancellableEvent) Reason() error { return e.reason }
+
 type EventHandler func(Event) error
+
+// ContextHandler is an event handler which receives the context of the publisher.
+type ContextHandler func(ctx context.Context, e Event) error
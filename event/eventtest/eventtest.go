@@ -0,0 +1,91 @@
+// Package eventtest provides utilities for testing event.Bus based code.
+package eventtest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/difof/syncity/event"
+)
+
+// Published is an event published on a FakeBus.
+type Published struct {
+	Topic string
+	Event event.Event
+	Time  time.Time
+}
+
+// FakeBus is an event.PubSub recording every published event. Events are delivered synchronously
+// to its subscribers, unless publishing the topic is set to fail with FailPublish.
+type FakeBus struct {
+	bus       *event.Bus
+	published []Published
+	failures  map[string]error
+	lock      sync.Mutex
+}
+
+var _ event.PubSub = (*FakeBus)(nil)
+
+// NewFakeBus creates a new empty FakeBus.
+func NewFakeBus() *FakeBus {
+	return &FakeBus{
+		bus:      event.NewBus(),
+		failures: make(map[string]error),
+	}
+}
+
+// FailPublish makes publishing on the topic return err without delivering the event.
+// A nil err restores the delivery.
+func (f *FakeBus) FailPublish(topic string, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if err == nil {
+		delete(f.failures, topic)
+		return
+	}
+
+	f.failures[topic] = err
+}
+
+func (f *FakeBus) Publish(topic string, e event.Event) error {
+	return f.PublishContext(context.Background(), topic, e)
+}
+
+func (f *FakeBus) PublishContext(ctx context.Context, topic string, e event.Event) error {
+	f.lock.Lock()
+	f.published = append(f.published, Published{Topic: topic, Event: e, Time: time.Now()})
+	err := f.failures[topic]
+	f.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return f.bus.PublishContext(ctx, topic, e)
+}
+
+func (f *FakeBus) Subscribe(ctx context.Context, topic string, handler event.EventHandler) *event.Subscription {
+	return f.bus.Subscribe(ctx, topic, handler)
+}
+
+func (f *FakeBus) SubscribeContext(ctx context.Context, topic string, handler event.ContextHandler) *event.Subscription {
+	return f.bus.SubscribeContext(ctx, topic, handler)
+}
+
+// Published returns the events published so far, including failed ones, in order.
+// Filtered by topics if any are given.
+func (f *FakeBus) Published(topics ...string) []Published {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return filter(f.published, topics)
+}
+
+// Reset forgets the published events.
+func (f *FakeBus) Reset() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.published = nil
+}
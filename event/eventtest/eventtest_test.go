@@ -0,0 +1,84 @@
+package eventtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/difof/syncity/event"
+)
+
+type userCreated struct {
+	Name string
+}
+
+func (e *userCreated) Topic() string {
+	return "user.created"
+}
+
+// register is code under test depending on a bus.
+func register(bus event.PubSub, name string) error {
+	return bus.Publish("user.created", &userCreated{Name: name})
+}
+
+func TestRecorder(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	b := event.NewAsyncBus(ctx, 2, 16)
+	r := Record(t, b, "user.created", "user.deleted")
+	other := Record(t, b, "user.banned")
+
+	for _, name := range []string{"a", "b"} {
+		if err := register(b, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r.ExpectEvents(&userCreated{Name: "a"}, &userCreated{Name: "b"})
+	r.ExpectTopics("user.created", "user.created")
+
+	if err := b.Publish("user.deleted", &userCreated{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := r.ExpectWithin(time.Second, "user.deleted"); got.Time.IsZero() {
+		t.Fatal("Expected recorded event to have a timestamp")
+	}
+
+	other.ExpectNone(10 * time.Millisecond)
+}
+
+func TestRecorder_Wildcard(t *testing.T) {
+	b := event.NewBus()
+	r := Record(t, b, "user.*")
+
+	if err := register(b, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	r.ExpectTopics("user.created")
+}
+
+func TestFakeBus(t *testing.T) {
+	f := NewFakeBus()
+	r := Record(t, f, "user.created")
+
+	if err := register(f, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("unavailable")
+	f.FailPublish("user.created", failure)
+
+	if err := register(f, "b"); !errors.Is(err, failure) {
+		t.Fatalf("Expected publish to fail with %v, got %v", failure, err)
+	}
+
+	if got := f.Published("user.created"); len(got) != 2 {
+		t.Fatalf("Expected 2 published events, got %d", len(got))
+	}
+
+	r.ExpectEvents(&userCreated{Name: "a"})
+}
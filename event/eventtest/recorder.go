@@ -0,0 +1,177 @@
+package eventtest
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/difof/syncity/event"
+)
+
+// Recorder is a subscriber which records the events of its topics and provides assertions on them.
+type Recorder struct {
+	t        testing.TB
+	received []Published
+	// changed is closed and replaced on every received event.
+	changed chan struct{}
+	lock    sync.Mutex
+	// Timeout is how long assertions wait for events. Defaults to one second.
+	Timeout time.Duration
+}
+
+// Record subscribes a new Recorder to the topics of the bus. The subscriptions are removed when the test finishes.
+// Topics may be wildcard patterns, events are recorded with the topic they were published on.
+func Record(t testing.TB, bus event.PubSub, topics ...string) *Recorder {
+	r := &Recorder{
+		t:       t,
+		changed: make(chan struct{}),
+		Timeout: time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	for _, topic := range topics {
+		bus.SubscribeContext(ctx, topic, func(ctx context.Context, e event.Event) error {
+			topic, _ := event.TopicFrom(ctx)
+			r.record(topic, e)
+			return nil
+		})
+	}
+
+	return r
+}
+
+func (r *Recorder) record(topic string, e event.Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.received = append(r.received, Published{Topic: topic, Event: e, Time: time.Now()})
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// wait waits at most d until ok returns true for the received events. Returns the received events.
+func (r *Recorder) wait(d time.Duration, ok func([]Published) bool) ([]Published, bool) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	for {
+		r.lock.Lock()
+		received, changed := slices.Clip(r.received), r.changed
+		r.lock.Unlock()
+
+		if ok(received) {
+			return received, true
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return received, false
+		}
+	}
+}
+
+// Received returns the events received so far, filtered by topics if any are given.
+func (r *Recorder) Received(topics ...string) []Published {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return filter(r.received, topics)
+}
+
+// WaitFor waits until n events are received in total and returns them.
+// Fails the test if they are not received within Timeout.
+func (r *Recorder) WaitFor(n int) []Published {
+	r.t.Helper()
+
+	received, ok := r.wait(r.Timeout, func(received []Published) bool { return len(received) >= n })
+	if !ok {
+		r.t.Fatalf("expected %d events, got %d", n, len(received))
+	}
+
+	return received[:n]
+}
+
+// ExpectTopics fails the test unless exactly the topics are received in order within Timeout.
+func (r *Recorder) ExpectTopics(topics ...string) {
+	r.t.Helper()
+
+	received := r.WaitFor(len(topics))
+	got := make([]string, len(received))
+	for i, p := range received {
+		got[i] = p.Topic
+	}
+
+	if !slices.Equal(got, topics) {
+		r.t.Fatalf("expected topics %v, got %v", topics, got)
+	}
+
+	r.expectCount(len(topics))
+}
+
+// ExpectEvents fails the test unless exactly events deeply equal to want are received in order within Timeout.
+func (r *Recorder) ExpectEvents(want ...event.Event) {
+	r.t.Helper()
+
+	received := r.WaitFor(len(want))
+	for i, p := range received {
+		if !reflect.DeepEqual(p.Event, want[i]) {
+			r.t.Fatalf("expected event %d to be %v, got %v", i, want[i], p.Event)
+		}
+	}
+
+	r.expectCount(len(want))
+}
+
+// expectCount fails the test if more than n events are received.
+func (r *Recorder) expectCount(n int) {
+	r.t.Helper()
+
+	if received := r.Received(); len(received) > n {
+		r.t.Fatalf("expected %d events, got %d", n, len(received))
+	}
+}
+
+// ExpectWithin fails the test unless an event of the topic is received within d, and returns the first one.
+func (r *Recorder) ExpectWithin(d time.Duration, topic string) Published {
+	r.t.Helper()
+
+	received, ok := r.wait(d, func(received []Published) bool { return len(filter(received, []string{topic})) > 0 })
+	if !ok {
+		r.t.Fatalf("expected event of %s within %s", topic, d)
+	}
+
+	return filter(received, []string{topic})[0]
+}
+
+// ExpectNone fails the test if an event of the topics, or any topic if none are given,
+// is received within wait. With synchronous buses a zero wait is enough.
+func (r *Recorder) ExpectNone(wait time.Duration, topics ...string) {
+	r.t.Helper()
+
+	received, found := r.wait(wait, func(received []Published) bool { return len(filter(received, topics)) > 0 })
+	if found {
+		r.t.Fatalf("expected no events, got %v", filter(received, topics))
+	}
+}
+
+// filter returns the events of the topics, or all events if no topics are given.
+func filter(published []Published, topics []string) []Published {
+	if len(topics) == 0 {
+		return slices.Clone(published)
+	}
+
+	var matched []Published
+	for _, p := range published {
+		if slices.Contains(topics, p.Topic) {
+			matched = append(matched, p)
+		}
+	}
+
+	return matched
+}
@@ -0,0 +1,332 @@
+package event
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms of NewMetrics.
+var DefaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// Histogram is a snapshot of a latency histogram.
+type Histogram struct {
+	// Buckets are the upper bounds of the buckets, Counts[i] is the number of calls taking at most Buckets[i].
+	// The last count is of calls longer than all buckets.
+	Buckets []time.Duration
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+}
+
+// Mean returns the mean latency.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / time.Duration(h.Count)
+}
+
+// HandlerStats are the counters of handler calls.
+type HandlerStats struct {
+	// Delivered is the number of handler calls, Failed of those returning an error including panics and timeouts.
+	// Every attempt of a retried delivery is a call of its own, see SubscribeConfig.Retry.
+	Delivered uint64
+	Failed    uint64
+	Panicked  uint64
+	Latency   Histogram
+}
+
+// TopicStats are the counters of a topic.
+type TopicStats struct {
+	Topic     string
+	Published uint64
+	HandlerStats
+}
+
+// SubscriberStats are the counters of a subscription.
+type SubscriberStats struct {
+	Id EventId
+	// Topic is the topic or pattern of the subscription.
+	Topic string
+	HandlerStats
+}
+
+// SlowCall is a handler call exceeding the slow threshold of Metrics.
+type SlowCall struct {
+	Topic      string
+	Subscriber EventId
+	// Subscription is the topic or pattern the handler subscribed to.
+	Subscription string
+	Event        Event
+	Duration     time.Duration
+}
+
+// histogram is a latency histogram updated atomically.
+type histogram struct {
+	buckets []time.Duration
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Int64
+}
+
+func newHistogram(buckets []time.Duration) *histogram {
+	return &histogram{buckets: buckets, counts: make([]atomic.Uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.Search(len(h.buckets), func(i int) bool { return d <= h.buckets[i] })
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+}
+
+func (h *histogram) snapshot() Histogram {
+	s := Histogram{
+		Buckets: h.buckets,
+		Counts:  make([]uint64, len(h.counts)),
+		Count:   h.count.Load(),
+		Sum:     time.Duration(h.sum.Load()),
+	}
+
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+	}
+
+	return s
+}
+
+// handlerCounters are the counters of handler calls updated atomically.
+type handlerCounters struct {
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+	panicked  atomic.Uint64
+	latency   *histogram
+}
+
+func (c *handlerCounters) observe(d time.Duration, err error) {
+	c.delivered.Add(1)
+	c.latency.observe(d)
+
+	if err == nil {
+		return
+	}
+
+	c.failed.Add(1)
+
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		c.panicked.Add(1)
+	}
+}
+
+func (c *handlerCounters) snapshot() HandlerStats {
+	return HandlerStats{
+		Delivered: c.delivered.Load(),
+		Failed:    c.failed.Load(),
+		Panicked:  c.panicked.Load(),
+		Latency:   c.latency.snapshot(),
+	}
+}
+
+type topicCounters struct {
+	published atomic.Uint64
+	handlerCounters
+}
+
+// subscriberKey identifies a subscription of an instrumented bus without holding on to it,
+// so removed subscriptions and their handlers can be collected.
+type subscriberKey struct {
+	bus *Bus
+	id  EventId
+}
+
+type subscriberCounters struct {
+	topic string
+	handlerCounters
+}
+
+// Metrics counts the publishes and handler calls of buses per topic and per subscription,
+// and reports slow handler calls. Install it on a bus with Instrument.
+//
+// A Metrics can instrument many buses, stats of topics add up across them while stats of subscriptions
+// are kept apart. Stats of removed subscriptions are kept until Reset, without the subscriptions themselves.
+type Metrics struct {
+	buckets     []time.Duration
+	topics      sync.Map
+	subscribers sync.Map
+
+	slow   time.Duration
+	onSlow func(SlowCall)
+	lock   sync.RWMutex
+}
+
+// NewMetrics creates a new Metrics with latency histograms of the given bucket upper bounds,
+// DefaultLatencyBuckets if none are given.
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	return &Metrics{buckets: buckets}
+}
+
+// OnSlow sets the function called with handler calls taking longer than threshold.
+// It is called on the goroutine of the handler, after the handler returns.
+func (m *Metrics) OnSlow(threshold time.Duration, f func(SlowCall)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.slow = threshold
+	m.onSlow = f
+}
+
+// Instrument adds the middleware collecting the metrics to the bus.
+// Handler calls are measured by the middleware added before Instrument as well.
+func (m *Metrics) Instrument(b *Bus) {
+	b.UsePublish(m.publishMiddleware)
+	b.UseHandler(m.handlerMiddleware)
+}
+
+func (m *Metrics) publishMiddleware(next PublishFunc) PublishFunc {
+	return func(ctx context.Context, topic string, event Event) error {
+		m.topic(topic).published.Add(1)
+		return next(ctx, topic, event)
+	}
+}
+
+func (m *Metrics) handlerMiddleware(next DeliverFunc) DeliverFunc {
+	return func(ctx context.Context, d Delivery) (err error) {
+		start := time.Now()
+		err = next(ctx, d)
+		elapsed := time.Since(start)
+
+		m.topic(d.Topic).observe(elapsed, err)
+		m.subscriber(d).observe(elapsed, err)
+		m.checkSlow(d, elapsed)
+
+		return
+	}
+}
+
+func (m *Metrics) checkSlow(d Delivery, elapsed time.Duration) {
+	m.lock.RLock()
+	threshold, f := m.slow, m.onSlow
+	m.lock.RUnlock()
+
+	if f == nil || elapsed < threshold {
+		return
+	}
+
+	f(SlowCall{
+		Topic:        d.Topic,
+		Subscriber:   d.Subscriber,
+		Subscription: d.sub.key,
+		Event:        d.Event,
+		Duration:     elapsed,
+	})
+}
+
+func (m *Metrics) topic(topic string) *topicCounters {
+	if c, ok := m.topics.Load(topic); ok {
+		return c.(*topicCounters)
+	}
+
+	c, _ := m.topics.LoadOrStore(topic, &topicCounters{handlerCounters: handlerCounters{latency: newHistogram(m.buckets)}})
+	return c.(*topicCounters)
+}
+
+func (m *Metrics) subscriber(d Delivery) *subscriberCounters {
+	key := subscriberKey{bus: d.sub.handle.bus, id: d.sub.id}
+	if c, ok := m.subscribers.Load(key); ok {
+		return c.(*subscriberCounters)
+	}
+
+	c, _ := m.subscribers.LoadOrStore(key, &subscriberCounters{
+		topic:           d.sub.key,
+		handlerCounters: handlerCounters{latency: newHistogram(m.buckets)},
+	})
+	return c.(*subscriberCounters)
+}
+
+// Topic returns the stats of the topic, ok is false if it was never published or delivered.
+func (m *Metrics) Topic(topic string) (stats TopicStats, ok bool) {
+	c, ok := m.topics.Load(topic)
+	if !ok {
+		return
+	}
+
+	return c.(*topicCounters).snapshot(topic), true
+}
+
+// Topics returns the stats of all topics published or delivered, sorted by topic.
+func (m *Metrics) Topics() (stats []TopicStats) {
+	m.topics.Range(func(k, v any) bool {
+		stats = append(stats, v.(*topicCounters).snapshot(k.(string)))
+		return true
+	})
+
+	slices.SortFunc(stats, func(a, b TopicStats) int { return cmp.Compare(a.Topic, b.Topic) })
+
+	return
+}
+
+// Subscriber returns the stats of the subscription, ok is false if it was never called.
+func (m *Metrics) Subscriber(sub *Subscription) (stats SubscriberStats, ok bool) {
+	c, ok := m.subscribers.Load(subscriberKey{bus: sub.bus, id: sub.sub.id})
+	if !ok {
+		return
+	}
+
+	return c.(*subscriberCounters).snapshot(sub.sub.id), true
+}
+
+// Subscribers returns the stats of all subscriptions called so far, ordered by id.
+// Ids are unique per bus only, subscriptions of different buses may share an id.
+func (m *Metrics) Subscribers() (stats []SubscriberStats) {
+	m.subscribers.Range(func(k, v any) bool {
+		stats = append(stats, v.(*subscriberCounters).snapshot(k.(subscriberKey).id))
+		return true
+	})
+
+	slices.SortStableFunc(stats, func(a, b SubscriberStats) int { return cmp.Compare(a.Id, b.Id) })
+
+	return
+}
+
+// Reset drops all stats.
+func (m *Metrics) Reset() {
+	m.topics.Range(func(k, _ any) bool {
+		m.topics.Delete(k)
+		return true
+	})
+
+	m.subscribers.Range(func(k, _ any) bool {
+		m.subscribers.Delete(k)
+		return true
+	})
+}
+
+func (c *topicCounters) snapshot(topic string) TopicStats {
+	return TopicStats{Topic: topic, Published: c.published.Load(), HandlerStats: c.handlerCounters.snapshot()}
+}
+
+func (c *subscriberCounters) snapshot(id EventId) SubscriberStats {
+	return SubscriberStats{Id: id, Topic: c.topic, HandlerStats: c.handlerCounters.snapshot()}
+}
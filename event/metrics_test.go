@@ -0,0 +1,116 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+	m := NewMetrics(time.Second)
+	m.Instrument(b)
+
+	var slow []SlowCall
+	m.OnSlow(10*time.Millisecond, func(c SlowCall) { slow = append(slow, c) })
+
+	ok := b.Subscribe(ctx, "user.*", func(e Event) error { return nil })
+	failing := b.Subscribe(ctx, "user.created", func(e Event) error { return errors.New("failed") })
+	panicking := b.Subscribe(ctx, "user.created", func(e Event) error { panic("boom") })
+	sleepy := b.Subscribe(ctx, "user.deleted", func(e Event) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+
+	for _, topic := range []string{"user.created", "user.created", "user.deleted", "order.created"} {
+		_ = b.Publish(topic, numberEvent{topic: topic})
+	}
+
+	created, _ := m.Topic("user.created")
+	if created.Published != 2 || created.Delivered != 6 || created.Failed != 4 || created.Panicked != 2 {
+		t.Fatalf("Unexpected stats of user.created: %+v", created)
+	}
+
+	if created.Latency.Count != 6 || created.Latency.Sum <= 0 {
+		t.Fatalf("Expected 6 calls in the latency histogram, got %+v", created.Latency)
+	}
+
+	if order, _ := m.Topic("order.created"); order.Published != 1 || order.Delivered != 0 {
+		t.Fatalf("Unexpected stats of order.created: %+v", order)
+	}
+
+	if s, _ := m.Subscriber(ok); s.Topic != "user.*" || s.Delivered != 3 || s.Failed != 0 {
+		t.Fatalf("Unexpected stats of wildcard subscriber: %+v", s)
+	}
+
+	if s, _ := m.Subscriber(failing); s.Failed != 2 || s.Panicked != 0 {
+		t.Fatalf("Unexpected stats of failing subscriber: %+v", s)
+	}
+
+	if s, _ := m.Subscriber(panicking); s.Failed != 2 || s.Panicked != 2 {
+		t.Fatalf("Unexpected stats of panicking subscriber: %+v", s)
+	}
+
+	if len(slow) != 1 || slow[0].Subscriber != sleepy.Id() || slow[0].Topic != "user.deleted" || slow[0].Duration < 20*time.Millisecond {
+		t.Fatalf("Expected a single slow call of the sleepy handler, got %+v", slow)
+	}
+
+	if got := len(m.Topics()); got != 3 {
+		t.Fatalf("Expected stats of 3 topics, got %d", got)
+	}
+}
+
+func TestMetrics_Buses(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetrics()
+
+	var subs []*Subscription
+	for _, fail := range []bool{false, true} {
+		fail := fail
+		b := NewBus()
+		m.Instrument(b)
+		subs = append(subs, b.Subscribe(ctx, "a", func(e Event) error {
+			if fail {
+				return errors.New("failed")
+			}
+			return nil
+		}))
+
+		_ = b.Publish("a", numberEvent{topic: "a"})
+	}
+
+	if subs[0].Id() != subs[1].Id() {
+		t.Fatal("Expected subscriptions of different buses to share an id")
+	}
+
+	first, _ := m.Subscriber(subs[0])
+	second, _ := m.Subscriber(subs[1])
+	if first.Delivered != 1 || first.Failed != 0 || second.Delivered != 1 || second.Failed != 1 {
+		t.Fatalf("Expected stats of subscriptions kept apart, got %+v and %+v", first, second)
+	}
+
+	if topic, _ := m.Topic("a"); topic.Published != 2 || topic.Failed != 1 {
+		t.Fatalf("Expected stats of the topic to add up, got %+v", topic)
+	}
+}
+
+func TestMetrics_Retry(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetrics()
+	b := NewBus()
+	m.Instrument(b)
+
+	sub := b.On("a").Retry(RetryPolicy{MaxAttempts: 3}).Do(ctx, func(e Event) error {
+		return errors.New("failed")
+	})
+
+	_ = b.Publish("a", numberEvent{topic: "a"})
+	sub.Unsubscribe()
+
+	// every attempt is a call, and stats outlive the subscription
+	if stats, ok := m.Subscriber(sub); !ok || stats.Delivered != 3 || stats.Failed != 3 {
+		t.Fatalf("Expected 3 failed calls, got %+v (%v)", stats, ok)
+	}
+}
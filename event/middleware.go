@@ -0,0 +1,117 @@
+package event
+
+import (
+	"context"
+	"runtime/debug"
+)
+
+// PublishFunc publishes an event on a topic.
+type PublishFunc func(ctx context.Context, topic string, event Event) error
+
+// PublishMiddleware wraps publishing of events, e.g. to log, authorize or trace them.
+// For async buses it wraps queueing the event.
+type PublishMiddleware func(next PublishFunc) PublishFunc
+
+// Delivery is an event being delivered to a subscriber.
+type Delivery struct {
+	Topic      string
+	Event      Event
+	Subscriber EventId
+	// Attempt is the number of the current call of the handler, starting from 1, see RetryPolicy.
+	Attempt int
+
+	sub *subscriber
+	// handler replaces the handler of the subscriber if set.
+	handler ContextHandler
+}
+
+// DeliverFunc delivers an event to a subscriber.
+type DeliverFunc func(ctx context.Context, d Delivery) error
+
+// HandlerMiddleware wraps calls of handlers, e.g. to measure, retry or recover them.
+// Panics and timeouts of handlers reach the middleware as *PanicError and *TimeoutError.
+type HandlerMiddleware func(next DeliverFunc) DeliverFunc
+
+// UsePublish adds middleware wrapping every publish. Middleware added first runs first.
+func (b *Bus) UsePublish(mw ...PublishMiddleware) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.publishMw = append(b.publishMw, mw...)
+	publish := PublishFunc(b.publishBase)
+	for i := len(b.publishMw) - 1; i >= 0; i-- {
+		publish = b.publishMw[i](publish)
+	}
+
+	b.setPublish(publish)
+}
+
+// UseHandler adds middleware wrapping every handler call. Middleware added first runs first.
+func (b *Bus) UseHandler(mw ...HandlerMiddleware) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.handlerMw = append(b.handlerMw, mw...)
+	d := DeliverFunc(deliver)
+	for i := len(b.handlerMw) - 1; i >= 0; i-- {
+		d = b.handlerMw[i](d)
+	}
+
+	b.setDeliver(d)
+}
+
+// setPublish replaces the publish chain, publishes already running keep the old one.
+func (b *Bus) setPublish(publish PublishFunc) {
+	b.publishFn.Store(&publish)
+}
+
+// setDeliver replaces the deliver chain, publishes already running keep the old one.
+func (b *Bus) setDeliver(deliver DeliverFunc) {
+	b.deliverFn.Store(&deliver)
+}
+
+// deliver is the innermost DeliverFunc calling the handler of the subscriber.
+// Handlers with a timeout are abandoned once it passes or ctx is done, the handler is
+// expected to observe its context and return.
+func deliver(ctx context.Context, d Delivery) error {
+	s := d.sub
+	handler := d.handler
+	if handler == nil {
+		handler = s.handler
+	}
+
+	if s.timeout <= 0 {
+		return invoke(ctx, handler, d.Event)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// abandoned handlers are still counted by the bus, so Drain and Close wait for them
+	bus := s.handle.bus
+	bus.begin()
+
+	result := make(chan error, 1)
+	go func() {
+		defer bus.end()
+		result <- invoke(ctx, handler, d.Event)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return &TimeoutError{Timeout: s.timeout, Err: ctx.Err()}
+	}
+}
+
+// invoke calls the handler, recovering panics as *PanicError.
+func invoke(ctx context.Context, handler ContextHandler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return handler(ctx, event)
+}
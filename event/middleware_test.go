@@ -0,0 +1,77 @@
+package event
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+func TestBus_Middleware(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	var calls []string
+	b.UsePublish(func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, topic string, event Event) error {
+			calls = append(calls, "publish "+topic)
+			return next(ctx, topic, event)
+		}
+	})
+
+	type observed struct {
+		d        Delivery
+		duration time.Duration
+		err      error
+	}
+	var deliveries []observed
+
+	b.UseHandler(func(next DeliverFunc) DeliverFunc {
+		return func(ctx context.Context, d Delivery) error {
+			calls = append(calls, "outer")
+			start := time.Now()
+			err := next(ctx, d)
+			deliveries = append(deliveries, observed{d: d, duration: time.Since(start), err: err})
+			return err
+		}
+	}, func(next DeliverFunc) DeliverFunc {
+		return func(ctx context.Context, d Delivery) error {
+			calls = append(calls, "inner")
+			return next(ctx, d)
+		}
+	})
+
+	slow := b.Subscribe(ctx, "test", func(e Event) error {
+		time.Sleep(10 * time.Millisecond)
+		return errFailed
+	})
+	b.Subscribe(ctx, "test", func(e Event) error {
+		panic("boom")
+	})
+
+	err := b.Publish("test", &testEvent{})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("Expected %v, got %v", errFailed, err)
+	}
+
+	if !slices.Equal(calls, []string{"publish test", "outer", "inner", "outer", "inner"}) {
+		t.Fatalf("Expected middleware to run in order, got %v", calls)
+	}
+
+	if len(deliveries) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", len(deliveries))
+	}
+
+	first := deliveries[0]
+	if first.d.Subscriber != slow.Id() || first.d.Topic != "test" || first.duration < 10*time.Millisecond || !errors.Is(first.err, errFailed) {
+		t.Fatalf("Unexpected observed delivery %+v", first)
+	}
+
+	var perr *PanicError
+	if !errors.As(deliveries[1].err, &perr) || perr.Value != "boom" {
+		t.Fatalf("Expected middleware to observe the panic, got %v", deliveries[1].err)
+	}
+}
@@ -0,0 +1,267 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/difof/errors"
+	"github.com/gofrs/uuid"
+)
+
+// OutboxEntry is an event waiting in an Outbox to be delivered to the bus.
+type OutboxEntry struct {
+	Id    uint64
+	Key   string
+	Topic string
+	Time  time.Time
+	Event Event
+}
+
+// Outbox is a durable queue of events to be delivered to the bus, see OutboxDispatcher.
+type Outbox interface {
+	// Add appends the event to the outbox. key identifies the event for deduplication,
+	// a random key is generated if empty.
+	Add(ctx context.Context, key, topic string, event Event) (OutboxEntry, error)
+
+	// Pending returns up to limit undelivered entries in the order they were added.
+	Pending(ctx context.Context, limit int) ([]OutboxEntry, error)
+
+	// MarkDelivered marks the entries as delivered, they are no longer pending.
+	MarkDelivered(ctx context.Context, ids ...uint64) error
+
+	// Compact drops the delivered entries from the storage.
+	Compact(ctx context.Context) error
+}
+
+type idempotencyKey struct{}
+
+// IdempotencyKey returns the key of the outbox entry being delivered if ctx belongs to one.
+// Delivery is at-least-once, so handlers can use the key to skip events they have already handled.
+func IdempotencyKey(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	return key, ok
+}
+
+// newOutboxKey returns key, or a new random key if empty.
+func newOutboxKey(key string) string {
+	if key != "" {
+		return key
+	}
+
+	return uuid.Must(uuid.NewV4()).String()
+}
+
+// OutboxDispatcher delivers the pending entries of an outbox to the bus with at-least-once semantics:
+// an entry is marked as delivered only after it is published without error, so a crash in between
+// delivers it again.
+//
+// Entries are delivered synchronously even on async buses, an entry is marked as delivered only once
+// all of its handlers succeeded. Handlers which succeeded are called again on each retry of the entry.
+type OutboxDispatcher struct {
+	bus      *Bus
+	outbox   Outbox
+	interval time.Duration
+	onError  func(error)
+
+	// BatchSize is the number of entries delivered on each round. Defaults to 100.
+	BatchSize int
+	// CompactThreshold is the number of delivered entries after which the outbox is compacted. Defaults to 1000.
+	CompactThreshold int
+	// MaxAttempts is the number of rounds an entry may fail in a row before it is parked: published as a
+	// *DeadLetter on DeadLetterTopic and marked as delivered, unblocking the entries after it.
+	// Zero retries entries forever.
+	MaxAttempts int
+
+	delivered int
+	// failures are the errors of the entries failing in a row, by id.
+	failures map[uint64][]error
+	lock     sync.Mutex
+}
+
+// NewOutboxDispatcher creates a new dispatcher delivering the outbox to the bus every interval.
+func NewOutboxDispatcher(bus *Bus, outbox Outbox, interval time.Duration) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		bus:              bus,
+		outbox:           outbox,
+		interval:         interval,
+		BatchSize:        100,
+		CompactThreshold: 1000,
+	}
+}
+
+// SetErrorHandler sets the function called with errors of delivering entries in Run.
+func (d *OutboxDispatcher) SetErrorHandler(f func(error)) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.onError = f
+}
+
+func (d *OutboxDispatcher) handleError(err error) {
+	d.lock.Lock()
+	f := d.onError
+	d.lock.Unlock()
+
+	if f != nil {
+		f(err)
+	}
+}
+
+// Run is a blocking function delivering the outbox every interval until ctx is done.
+// Failed entries are retried on the next round, blocking the entries after them to keep the order,
+// see MaxAttempts. Failures are passed to the function set by SetErrorHandler as *OutboxError.
+func (d *OutboxDispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.Dispatch(ctx); err != nil && ctx.Err() == nil {
+			d.handleError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch delivers the pending entries in order until none are left or one fails.
+// Returns the number of delivered entries, and an *OutboxError of the failed entry if any.
+// An entry parked by this round fails it as well, the next round goes on after it.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context) (n int, err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	defer func() {
+		if d.delivered >= d.CompactThreshold && err == nil {
+			if err = d.outbox.Compact(ctx); err == nil {
+				d.delivered = 0
+			}
+		}
+	}()
+
+	for {
+		var entries []OutboxEntry
+		if entries, err = d.outbox.Pending(ctx, d.BatchSize); err != nil || len(entries) == 0 {
+			return
+		}
+
+		for _, entry := range entries {
+			pctx := withSync(context.WithValue(ctx, idempotencyKey{}, entry.Key))
+			if err = d.bus.publish(pctx, entry.Topic, entry.Event); err != nil {
+				err = d.fail(ctx, entry, err)
+				return
+			}
+
+			delete(d.failures, entry.Id)
+
+			if err = d.outbox.MarkDelivered(ctx, entry.Id); err != nil {
+				return
+			}
+
+			n++
+			d.delivered++
+		}
+	}
+}
+
+// fail records the failure of the entry, parking it once it failed MaxAttempts rounds in a row.
+func (d *OutboxDispatcher) fail(ctx context.Context, entry OutboxEntry, err error) error {
+	if d.failures == nil {
+		d.failures = make(map[uint64][]error)
+	}
+
+	errs := append(d.failures[entry.Id], err)
+	d.failures[entry.Id] = errs
+
+	oerr := &OutboxError{Entry: entry, Attempts: len(errs), Err: err}
+	if d.MaxAttempts <= 0 || oerr.Attempts < d.MaxAttempts {
+		return oerr
+	}
+
+	letter := &DeadLetter{Event: entry.Event, EventTopic: entry.Topic, Errors: errs, topic: DeadLetterTopic}
+	if perr := d.bus.PublishContext(ctx, letter.topic, letter); perr != nil {
+		return errors.Wrapf(perr, "failed to park outbox entry %d", entry.Id)
+	}
+
+	if merr := d.outbox.MarkDelivered(ctx, entry.Id); merr != nil {
+		return merr
+	}
+
+	delete(d.failures, entry.Id)
+	d.delivered++
+	oerr.Parked = true
+
+	return oerr
+}
+
+// MemoryOutbox is an in-memory Outbox, useful in tests.
+type MemoryOutbox struct {
+	entries []OutboxEntry
+	done    map[uint64]bool
+	lastId  uint64
+	lock    sync.Mutex
+}
+
+// NewMemoryOutbox creates a new empty MemoryOutbox.
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{done: make(map[uint64]bool)}
+}
+
+func (o *MemoryOutbox) Add(_ context.Context, key, topic string, event Event) (OutboxEntry, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.lastId++
+	entry := OutboxEntry{Id: o.lastId, Key: newOutboxKey(key), Topic: topic, Time: time.Now(), Event: event}
+	o.entries = append(o.entries, entry)
+
+	return entry, nil
+}
+
+func (o *MemoryOutbox) Pending(_ context.Context, limit int) (entries []OutboxEntry, err error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for _, entry := range o.entries {
+		if len(entries) >= limit {
+			break
+		}
+
+		if !o.done[entry.Id] {
+			entries = append(entries, entry)
+		}
+	}
+
+	return
+}
+
+func (o *MemoryOutbox) MarkDelivered(_ context.Context, ids ...uint64) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for _, id := range ids {
+		o.done[id] = true
+	}
+
+	return nil
+}
+
+func (o *MemoryOutbox) Compact(context.Context) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	pending := o.entries[:0]
+	for _, entry := range o.entries {
+		if !o.done[entry.Id] {
+			pending = append(pending, entry)
+		}
+	}
+
+	o.entries = pending
+	o.done = make(map[uint64]bool)
+
+	return nil
+}
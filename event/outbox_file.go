@@ -0,0 +1,208 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+// outboxRecord is a line of a FileOutbox, either adding an entry, marking one as delivered,
+// or keeping the last id handed out when compacting.
+type outboxRecord struct {
+	Op    string    `json:"op"`
+	Id    uint64    `json:"id"`
+	Key   string    `json:"key,omitempty"`
+	Topic string    `json:"topic,omitempty"`
+	Time  time.Time `json:"time,omitempty"`
+	Data  []byte    `json:"data,omitempty"`
+}
+
+const (
+	outboxAdd  = "add"
+	outboxDone = "done"
+	outboxLast = "last"
+)
+
+// FileOutbox is an Outbox backed by an append-only file of JSON lines, encoding events with a Codec.
+// Every write is synced to disk before returning. Pending entries are kept in memory.
+type FileOutbox struct {
+	file    *os.File
+	path    string
+	codec   Codec
+	lastId  uint64
+	pending []outboxRecord
+	lock    sync.Mutex
+}
+
+// OpenFileOutbox opens or creates the outbox file at path.
+// A partially written last line, left by a crash, is truncated.
+func OpenFileOutbox(path string, codec Codec) (o *FileOutbox, err error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open outbox %s", path)
+	}
+
+	o = &FileOutbox{file: file, path: path, codec: codec}
+
+	var size int64
+	err = readLines(file, -1, func(line []byte, offset, end int64) error {
+		var rec outboxRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return errors.Wrapf(err, "corrupted record at offset %d", offset)
+		}
+
+		o.apply(rec)
+		size = end
+		return nil
+	})
+
+	if err == nil {
+		err = file.Truncate(size)
+	}
+
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrapf(err, "failed to load outbox %s", path)
+	}
+
+	return
+}
+
+// apply applies the record to the pending entries.
+func (o *FileOutbox) apply(rec outboxRecord) {
+	switch rec.Op {
+	case outboxAdd:
+		o.pending = append(o.pending, rec)
+		o.lastId = max(o.lastId, rec.Id)
+	case outboxDone:
+		for i, p := range o.pending {
+			if p.Id == rec.Id {
+				o.pending = append(o.pending[:i], o.pending[i+1:]...)
+				break
+			}
+		}
+	case outboxLast:
+		o.lastId = max(o.lastId, rec.Id)
+	}
+}
+
+// write appends and syncs the records.
+func (o *FileOutbox) write(recs ...outboxRecord) error {
+	var buf []byte
+	for _, rec := range recs {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return errors.Wrap(err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	if _, err := o.file.Write(buf); err != nil {
+		return errors.Wrapf(err, "failed to write outbox %s", o.path)
+	}
+
+	return errors.Catchf(o.file.Sync(), "failed to sync outbox %s", o.path)
+}
+
+func (o *FileOutbox) Add(_ context.Context, key, topic string, event Event) (entry OutboxEntry, err error) {
+	data, err := o.codec.Encode(event)
+	if err != nil {
+		return entry, errors.Wrapf(err, "failed to encode event of %s", topic)
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	rec := outboxRecord{Op: outboxAdd, Id: o.lastId + 1, Key: newOutboxKey(key), Topic: topic, Time: time.Now(), Data: data}
+	if err = o.write(rec); err != nil {
+		return
+	}
+
+	o.apply(rec)
+
+	return OutboxEntry{Id: rec.Id, Key: rec.Key, Topic: rec.Topic, Time: rec.Time, Event: event}, nil
+}
+
+func (o *FileOutbox) Pending(_ context.Context, limit int) (entries []OutboxEntry, err error) {
+	o.lock.Lock()
+	pending := slices.Clone(o.pending[:min(limit, len(o.pending))])
+	o.lock.Unlock()
+
+	for _, rec := range pending {
+		event, err := o.codec.Decode(rec.Topic, rec.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode outbox entry %d", rec.Id)
+		}
+
+		entries = append(entries, OutboxEntry{Id: rec.Id, Key: rec.Key, Topic: rec.Topic, Time: rec.Time, Event: event})
+	}
+
+	return
+}
+
+func (o *FileOutbox) MarkDelivered(_ context.Context, ids ...uint64) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	recs := make([]outboxRecord, len(ids))
+	for i, id := range ids {
+		recs[i] = outboxRecord{Op: outboxDone, Id: id}
+	}
+
+	if err := o.write(recs...); err != nil {
+		return err
+	}
+
+	for _, rec := range recs {
+		o.apply(rec)
+	}
+
+	return nil
+}
+
+// Compact rewrites the outbox file with only the pending entries, keeping the last id handed out
+// so ids are not reused after reopening.
+func (o *FileOutbox) Compact(context.Context) (err error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	tmpPath := o.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", tmpPath)
+	}
+
+	file := o.file
+	o.file = tmp
+
+	recs := append([]outboxRecord{{Op: outboxLast, Id: o.lastId}}, o.pending...)
+	if err = o.write(recs...); err == nil {
+		err = os.Rename(tmpPath, o.path)
+	}
+
+	if err != nil {
+		o.file = file
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return errors.Wrapf(err, "failed to compact outbox %s", o.path)
+	}
+
+	return file.Close()
+}
+
+// Close closes the outbox file.
+func (o *FileOutbox) Close() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.file.Close()
+}
@@ -0,0 +1,168 @@
+package event
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+func TestFileOutbox(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	codec := NewJSONCodec(&userCreated{})
+
+	outbox, err := OpenFileOutbox(path, codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err = outbox.Add(ctx, "key-"+name, "user.created", &userCreated{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// reopening keeps the undelivered entries, as after a crash
+	_ = outbox.Close()
+	if outbox, err = OpenFileOutbox(path, codec); err != nil {
+		t.Fatal(err)
+	}
+	defer outbox.Close()
+
+	b := NewBus()
+	keys := map[string]string{}
+	failOnce := true
+	b.SubscribeContext(ctx, "user.created", func(ctx context.Context, e Event) error {
+		name := e.(*userCreated).Name
+		if name == "b" && failOnce {
+			failOnce = false
+			return errors.New("unavailable")
+		}
+
+		keys[name], _ = IdempotencyKey(ctx)
+		return nil
+	})
+
+	d := NewOutboxDispatcher(b, outbox, 0)
+	d.CompactThreshold = 3
+
+	if n, err := d.Dispatch(ctx); err == nil || n != 1 {
+		t.Fatalf("Expected dispatch to stop at the failing entry after 1 delivery, got %d (%v)", n, err)
+	}
+
+	if n, err := d.Dispatch(ctx); err != nil || n != 2 {
+		t.Fatalf("Expected the remaining 2 entries to be delivered, got %d (%v)", n, err)
+	}
+
+	for _, name := range []string{"a", "b", "c"} {
+		if keys[name] != "key-"+name {
+			t.Fatalf("Expected idempotency key of %s, got %q", name, keys[name])
+		}
+	}
+
+	if pending, _ := outbox.Pending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("Expected no pending entries, got %d", len(pending))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("Expected outbox to be compacted to its last id, got %q", data)
+	}
+
+	// ids are not reused after reopening a compacted outbox
+	_ = outbox.Close()
+	if outbox, err = OpenFileOutbox(path, codec); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry, err := outbox.Add(ctx, "", "user.created", &userCreated{Name: "d"}); err != nil || entry.Id != 4 {
+		t.Fatalf("Expected id 4 after reopening, got %d (%v)", entry.Id, err)
+	}
+}
+
+func TestOutboxDispatcher_MaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutbox()
+	b := NewBus()
+
+	var letters []*DeadLetter
+	b.Subscribe(ctx, DeadLetterTopic, func(e Event) error {
+		letters = append(letters, e.(*DeadLetter))
+		return nil
+	})
+
+	var delivered []string
+	b.Subscribe(ctx, "user.created", func(e Event) error {
+		name := e.(*userCreated).Name
+		if name == "poison" {
+			return errors.New("invalid")
+		}
+
+		delivered = append(delivered, name)
+		return nil
+	})
+
+	for _, name := range []string{"poison", "a"} {
+		if _, err := outbox.Add(ctx, "", "user.created", &userCreated{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d := NewOutboxDispatcher(b, outbox, 0)
+	d.MaxAttempts = 2
+
+	var oerr *OutboxError
+	for attempt := 1; attempt <= 2; attempt++ {
+		if _, err := d.Dispatch(ctx); !errors.As(err, &oerr) || oerr.Entry.Id != 1 || oerr.Attempts != attempt {
+			t.Fatalf("Expected entry 1 to fail attempt %d, got %v", attempt, err)
+		}
+	}
+
+	if !oerr.Parked || len(letters) != 1 || letters[0].Event.(*userCreated).Name != "poison" || len(letters[0].Errors) != 2 {
+		t.Fatalf("Expected entry 1 to be parked as a dead letter, got %v and %v", oerr, letters)
+	}
+
+	if n, err := d.Dispatch(ctx); err != nil || n != 1 || len(delivered) != 1 {
+		t.Fatalf("Expected the entry after the parked one to be delivered, got %d (%v)", n, err)
+	}
+}
+
+func TestOutboxDispatcher_AsyncBus(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	b := NewAsyncBus(ctx, 1, 4)
+	b.SetErrorHandler(func(err error) { t.Errorf("Expected handler errors to reach the dispatcher, got %v", err) })
+
+	failOnce := true
+	b.Subscribe(ctx, "user.created", func(e Event) error {
+		if failOnce {
+			failOnce = false
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+
+	outbox := NewMemoryOutbox()
+	if _, err := outbox.Add(ctx, "", "user.created", &userCreated{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewOutboxDispatcher(b, outbox, 0)
+	if n, err := d.Dispatch(ctx); err == nil || n != 0 {
+		t.Fatalf("Expected the failed entry to stay pending, got %d (%v)", n, err)
+	}
+
+	if n, err := d.Dispatch(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected the entry to be delivered on retry, got %d (%v)", n, err)
+	}
+}
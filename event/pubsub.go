@@ -0,0 +1,13 @@
+package event
+
+import "context"
+
+// PubSub is the publishing and subscribing side of a Bus, for code which can be tested with a fake bus.
+type PubSub interface {
+	Publish(topic string, event Event) error
+	PublishContext(ctx context.Context, topic string, event Event) error
+	Subscribe(ctx context.Context, topic string, handler EventHandler) *Subscription
+	SubscribeContext(ctx context.Context, topic string, handler ContextHandler) *Subscription
+}
+
+var _ PubSub = (*Bus)(nil)
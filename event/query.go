@@ -0,0 +1,128 @@
+package event
+
+import (
+	"context"
+	"sync"
+
+	"github.com/difof/errors"
+)
+
+// query collects the results of responders for a request published by Ask.
+type query struct {
+	first   bool
+	results []any
+	// answered is set on the first result if only the first one is wanted.
+	answered bool
+	lock     sync.Mutex
+}
+
+type queryKey struct{}
+
+// queryFrom returns the query if ctx belongs to the publish of its request, see unmarked.
+func queryFrom(ctx context.Context) *query {
+	q, _ := ctx.Value(queryKey{}).(*query)
+	return q
+}
+
+// add adds the result.
+func (q *query) add(result any) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.results = append(q.results, result)
+	q.answered = q.first
+}
+
+// done reports whether the query needs no more results.
+func (q *query) done() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.answered
+}
+
+// Respond subscribes the responder to requests of type T. Responders answering a request
+// return ErrNoResult to decline it. See Ask. T must be a concrete type, see Subscribe.
+//
+// Requests published with Publish rather than Ask are handled as well, with their results discarded.
+func Respond[T Event, R any](bus *Bus, responder func(context.Context, T) (R, error)) *Subscription {
+	return Subscribe(bus, func(ctx context.Context, request T) error {
+		q := queryFrom(ctx)
+		if q != nil && q.done() {
+			return nil
+		}
+
+		result, err := responder(ctx, request)
+		if errors.Is(err, ErrNoResult) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if q != nil {
+			q.add(result)
+		}
+
+		return nil
+	})
+}
+
+// ask publishes the request synchronously, even on async buses, and returns the results of type R.
+// Returns ctx.Err() without waiting for the responders once ctx is done.
+func ask[R any](ctx context.Context, bus *Bus, request Event, first bool) (results []R, err error) {
+	q := &query{first: first}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.publish(context.WithValue(ctx, queryKey{}, q), request.Topic(), request)
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, result := range q.results {
+		if r, ok := result.(R); ok {
+			results = append(results, r)
+		}
+	}
+
+	return
+}
+
+// Ask publishes the request and returns the first result of type R, skipping the remaining responders.
+// Other handlers of the request are called as usual.
+// Returns ErrNoResult if no responder answered, or the error of the failed responders.
+func Ask[R any](ctx context.Context, bus *Bus, request Event) (result R, err error) {
+	results, err := ask[R](ctx, bus, request, true)
+	if len(results) > 0 {
+		return results[0], nil
+	}
+
+	if err == nil {
+		err = ErrNoResult
+	}
+
+	return
+}
+
+// AskAll publishes the request and returns the results of type R of all responders in order,
+// along with the error of the failed responders if any.
+func AskAll[R any](ctx context.Context, bus *Bus, request Event) ([]R, error) {
+	return ask[R](ctx, bus, request, false)
+}
+
+// AskReduce publishes the request and reduces the results of type R of all responders into acc.
+func AskReduce[R, A any](ctx context.Context, bus *Bus, request Event, acc A, reduce func(A, R) A) (A, error) {
+	results, err := ask[R](ctx, bus, request, false)
+	for _, r := range results {
+		acc = reduce(acc, r)
+	}
+
+	return acc, err
+}
@@ -0,0 +1,131 @@
+package event
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+type paymentQuery struct {
+	Method string
+}
+
+func (q *paymentQuery) Topic() string {
+	return "payment.query"
+}
+
+func TestAsk(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	b := NewAsyncBus(ctx, 2, 2)
+
+	provider := func(name string, methods ...string) func(context.Context, *paymentQuery) (string, error) {
+		return func(ctx context.Context, q *paymentQuery) (string, error) {
+			if !slices.Contains(methods, q.Method) {
+				return "", ErrNoResult
+			}
+			return name, nil
+		}
+	}
+
+	Respond(b, provider("stripe", "card"))
+	Respond(b, provider("paypal", "paypal"))
+	Respond(b, provider("adyen", "card", "paypal"))
+
+	if first, err := Ask[string](ctx, b, &paymentQuery{Method: "card"}); err != nil || first != "stripe" {
+		t.Fatalf("Expected stripe, got %q (%v)", first, err)
+	}
+
+	if all, err := AskAll[string](ctx, b, &paymentQuery{Method: "paypal"}); err != nil || !slices.Equal(all, []string{"paypal", "adyen"}) {
+		t.Fatalf("Expected paypal and adyen, got %v (%v)", all, err)
+	}
+
+	count, err := AskReduce(ctx, b, &paymentQuery{Method: "card"}, 0, func(n int, _ string) int { return n + 1 })
+	if err != nil || count != 2 {
+		t.Fatalf("Expected 2 card providers, got %d (%v)", count, err)
+	}
+
+	if _, err = Ask[string](ctx, b, &paymentQuery{Method: "cash"}); !errors.Is(err, ErrNoResult) {
+		t.Fatalf("Expected %v, got %v", ErrNoResult, err)
+	}
+}
+
+func TestAsk_Deadline(t *testing.T) {
+	b := NewBus()
+
+	Respond(b, func(ctx context.Context, q *paymentQuery) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := Ask[string](ctx, b, &paymentQuery{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestAsk_OtherHandlers(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	responders := 0
+	for _, name := range []string{"stripe", "adyen"} {
+		name := name
+		Respond(b, func(ctx context.Context, q *paymentQuery) (string, error) {
+			responders++
+			return name, nil
+		})
+	}
+
+	audited := 0
+	b.Subscribe(ctx, "payment.query", func(e Event) error {
+		audited++
+		return nil
+	})
+
+	if first, err := Ask[string](ctx, b, &paymentQuery{Method: "card"}); err != nil || first != "stripe" {
+		t.Fatalf("Expected stripe, got %q (%v)", first, err)
+	}
+
+	if responders != 1 || audited != 1 {
+		t.Fatalf("Expected a single responder and the audit handler to be called, got %d and %d", responders, audited)
+	}
+}
+
+type lookupQuery struct {
+	V any
+}
+
+func (q lookupQuery) Topic() string {
+	return "lookup.query"
+}
+
+func TestAsk_UncomparableRequest(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	b := NewAsyncBus(ctx, 1, 4)
+
+	Respond(b, func(ctx context.Context, q lookupQuery) (int, error) {
+		if q.V == "nested" {
+			return 0, nil
+		}
+
+		// publishing from a responder is not part of the query, even with a request of the same type
+		if err := Publish(ctx, b, lookupQuery{V: "nested"}); err != nil {
+			return 0, err
+		}
+
+		return len(q.V.([]int)), nil
+	})
+
+	if n, err := Ask[int](ctx, b, lookupQuery{V: []int{1, 2, 3}}); err != nil || n != 3 {
+		t.Fatalf("Expected 3, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,308 @@
+package event
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// subscriber is a handler subscribed to a topic or to a type of events.
+type subscriber struct {
+	id       EventId
+	key      string
+	priority int
+	handler  ContextHandler
+	timeout  time.Duration
+	retry    *RetryPolicy
+	// deadLetter is the topic failed events are published on, if any.
+	deadLetter string
+	handle     *Subscription
+	// pacer holds back deliveries if set.
+	pacer   pacer
+	removed atomic.Bool
+	// remaining is the number of events left to deliver if limited.
+	limited   bool
+	remaining atomic.Int64
+}
+
+// acquire reports whether the next event is to be delivered to the subscriber.
+// Unsubscribes limited subscribers on their last event.
+func (s *subscriber) acquire() bool {
+	if s.removed.Load() {
+		return false
+	}
+
+	if !s.limited {
+		return true
+	}
+
+	n := s.remaining.Add(-1)
+	if n == 0 {
+		s.handle.remove()
+	}
+
+	return n >= 0
+}
+
+// before reports whether s is called before o: higher priority first, then in subscription order.
+func (s *subscriber) before(o *subscriber) bool {
+	if s.priority != o.priority {
+		return s.priority > o.priority
+	}
+
+	return s.id < o.id
+}
+
+func compareSubscribers(a, b *subscriber) int {
+	switch {
+	case a.before(b):
+		return -1
+	case b.before(a):
+		return 1
+	}
+
+	return 0
+}
+
+// registry keeps the subscribers of each key in the order they are called.
+//
+// Every key holds an immutable snapshot of its subscribers, replaced atomically on each change,
+// so match never blocks and is safe to call concurrently with add and remove.
+// Keys of wildcard patterns are kept in an immutable trie of their segments, see Bus.
+// Calls of add and remove must be serialized by the caller.
+type registry struct {
+	// keys is replaced only when a key is added or removed, changes of subscribers only swap the snapshot of the key.
+	keys      atomic.Pointer[map[string]*snapshot]
+	wildcards atomic.Pointer[trieNode]
+}
+
+// snapshot holds the subscribers of a key.
+type snapshot = atomic.Pointer[[]*subscriber]
+
+func newRegistry() (r *registry) {
+	r = &registry{}
+	r.keys.Store(&map[string]*snapshot{})
+	r.wildcards.Store(&trieNode{})
+
+	return
+}
+
+// add inserts the subscriber in order.
+func (r *registry) add(s *subscriber) {
+	if isPattern(s.key) {
+		r.wildcards.Store(r.wildcards.Load().with(strings.Split(s.key, "."), s))
+		return
+	}
+
+	keys := *r.keys.Load()
+	if snap, ok := keys[s.key]; ok {
+		subs := insertSubscriber(*snap.Load(), s)
+		snap.Store(&subs)
+		return
+	}
+
+	snap := new(snapshot)
+	snap.Store(&[]*subscriber{s})
+
+	keys = maps.Clone(keys)
+	keys[s.key] = snap
+	r.keys.Store(&keys)
+}
+
+// remove removes the subscriber of the key with the given id.
+func (r *registry) remove(key string, id EventId) {
+	if isPattern(key) {
+		if root := r.wildcards.Load().without(strings.Split(key, "."), id); root != nil {
+			r.wildcards.Store(root)
+		} else {
+			r.wildcards.Store(&trieNode{})
+		}
+		return
+	}
+
+	keys := *r.keys.Load()
+	snap, ok := keys[key]
+	if !ok {
+		return
+	}
+
+	subs, ok := removeSubscriber(*snap.Load(), id)
+	if !ok {
+		return
+	}
+
+	snap.Store(&subs)
+	if len(subs) == 0 {
+		// readers still holding the old map see the empty snapshot
+		keys = maps.Clone(keys)
+		delete(keys, key)
+		r.keys.Store(&keys)
+	}
+}
+
+// match returns the subscribers of the topic, of the patterns matching the topic and of the other keys
+// in the order they are called. The returned slice is owned by the caller.
+func (r *registry) match(topic string, keys ...string) (subs []*subscriber) {
+	snaps := *r.keys.Load()
+	load := func(key string) []*subscriber {
+		if snap, ok := snaps[key]; ok {
+			return *snap.Load()
+		}
+
+		return nil
+	}
+
+	subs = append(subs, load(topic)...)
+
+	merged := false
+	for _, key := range keys {
+		if s := load(key); len(s) > 0 {
+			merged = merged || len(subs) > 0
+			subs = append(subs, s...)
+		}
+	}
+
+	// the subscribers of patterns come from many nodes and may match more than once
+	if root := r.wildcards.Load(); len(root.children) > 0 {
+		n := len(subs)
+		subs = root.match(strings.Split(topic, "."), subs)
+		merged = merged || len(subs) > n
+	}
+
+	if merged {
+		slices.SortFunc(subs, compareSubscribers)
+		subs = slices.Compact(subs)
+	}
+
+	return
+}
+
+// all returns the subscribers of all keys.
+// The returned slice is owned by the caller.
+func (r *registry) all() (subs []*subscriber) {
+	for _, snap := range *r.keys.Load() {
+		subs = append(subs, *snap.Load()...)
+	}
+
+	return r.wildcards.Load().all(subs)
+}
+
+// insertSubscriber returns a copy of subs with the subscriber inserted in order.
+func insertSubscriber(subs []*subscriber, s *subscriber) []*subscriber {
+	i, _ := slices.BinarySearchFunc(subs, s, compareSubscribers)
+	return slices.Insert(slices.Clip(subs), i, s)
+}
+
+// removeSubscriber returns a copy of subs without the subscriber with the given id, ok is false if not found.
+func removeSubscriber(subs []*subscriber, id EventId) ([]*subscriber, bool) {
+	i := slices.IndexFunc(subs, func(s *subscriber) bool { return s.id == id })
+	if i < 0 {
+		return subs, false
+	}
+
+	return slices.Delete(slices.Clone(subs), i, i+1), true
+}
+
+const (
+	// wildcardOne matches exactly one segment of a topic.
+	wildcardOne = "*"
+	// wildcardAny matches zero or more segments of a topic.
+	wildcardAny = "**"
+)
+
+// isPattern reports whether any segment of the key is a wildcard.
+func isPattern(key string) bool {
+	for _, segment := range strings.Split(key, ".") {
+		if segment == wildcardOne || segment == wildcardAny {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trieNode is a segment of wildcard patterns, holding the subscribers of the patterns ending on it.
+// Nodes are immutable, changes copy the nodes on the path of the pattern.
+type trieNode struct {
+	children map[string]*trieNode
+	subs     []*subscriber
+}
+
+// with returns a copy of n with the subscriber added to the pattern.
+func (n *trieNode) with(segments []string, s *subscriber) *trieNode {
+	c := &trieNode{children: maps.Clone(n.children), subs: n.subs}
+	if len(segments) == 0 {
+		c.subs = insertSubscriber(c.subs, s)
+		return c
+	}
+
+	child, ok := c.children[segments[0]]
+	if !ok {
+		child = &trieNode{}
+	}
+
+	if c.children == nil {
+		c.children = make(map[string]*trieNode)
+	}
+
+	c.children[segments[0]] = child.with(segments[1:], s)
+
+	return c
+}
+
+// without returns a copy of n without the subscriber of the pattern, pruning the nodes left empty.
+// Returns nil if the copy is empty.
+func (n *trieNode) without(segments []string, id EventId) *trieNode {
+	c := &trieNode{children: n.children, subs: n.subs}
+	if len(segments) == 0 {
+		c.subs, _ = removeSubscriber(c.subs, id)
+	} else if child, ok := n.children[segments[0]]; ok {
+		c.children = maps.Clone(n.children)
+		if child = child.without(segments[1:], id); child != nil {
+			c.children[segments[0]] = child
+		} else {
+			delete(c.children, segments[0])
+		}
+	}
+
+	if len(c.subs) == 0 && len(c.children) == 0 {
+		return nil
+	}
+
+	return c
+}
+
+// match appends the subscribers of the patterns matching the segments.
+func (n *trieNode) match(segments []string, subs []*subscriber) []*subscriber {
+	if rest, ok := n.children[wildcardAny]; ok {
+		for i := 0; i <= len(segments); i++ {
+			subs = rest.match(segments[i:], subs)
+		}
+	}
+
+	if len(segments) == 0 {
+		return append(subs, n.subs...)
+	}
+
+	if child, ok := n.children[segments[0]]; ok {
+		subs = child.match(segments[1:], subs)
+	}
+
+	if one, ok := n.children[wildcardOne]; ok {
+		subs = one.match(segments[1:], subs)
+	}
+
+	return subs
+}
+
+// all appends the subscribers of all patterns.
+func (n *trieNode) all(subs []*subscriber) []*subscriber {
+	subs = append(subs, n.subs...)
+	for _, child := range n.children {
+		subs = child.all(subs)
+	}
+
+	return subs
+}
@@ -0,0 +1,246 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBus_Wildcards(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	received := map[string][]string{}
+	for _, pattern := range []string{"user.created", "user.*", "user.**", "**", "*.created", "user.**.updated", "**.**"} {
+		pattern := pattern
+		b.Subscribe(ctx, pattern, func(e Event) error {
+			received[pattern] = append(received[pattern], e.(numberEvent).topic)
+			return nil
+		})
+	}
+
+	for _, topic := range []string{"user", "user.created", "user.profile.updated", "order.created"} {
+		if err := b.Publish(topic, numberEvent{topic: topic}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string][]string{
+		"user.created":    {"user.created"},
+		"user.*":          {"user.created"},
+		"user.**":         {"user", "user.created", "user.profile.updated"},
+		"**":              {"user", "user.created", "user.profile.updated", "order.created"},
+		"*.created":       {"user.created", "order.created"},
+		"user.**.updated": {"user.profile.updated"},
+		"**.**":           {"user", "user.created", "user.profile.updated", "order.created"},
+	}
+
+	for pattern, topics := range want {
+		if !slices.Equal(received[pattern], topics) {
+			t.Errorf("Expected %s to receive %v, got %v", pattern, topics, received[pattern])
+		}
+	}
+}
+
+func TestBus_WildcardOrder(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	var order []int
+	handler := func(n int) EventHandler {
+		return func(e Event) error {
+			order = append(order, n)
+			return nil
+		}
+	}
+
+	b.Subscribe(ctx, "**", handler(1))
+	b.Subscribe(ctx, "user.created", handler(2))
+	sub := b.On("user.*").Priority(1).Do(ctx, handler(3))
+	b.Subscribe(ctx, "user.*", handler(4))
+
+	if err := b.Publish("user.created", numberEvent{topic: "user.created"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{3, 1, 2, 4}; !slices.Equal(order, want) {
+		t.Fatalf("Expected handlers in order %v, got %v", want, order)
+	}
+
+	sub.Unsubscribe()
+	order = nil
+
+	if err := b.Publish("user.created", numberEvent{topic: "user.created"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{1, 2, 4}; !slices.Equal(order, want) {
+		t.Fatalf("Expected handlers in order %v after unsubscribe, got %v", want, order)
+	}
+}
+
+// mutexRegistry is the previous registry guarded by the lock of the bus, kept to compare against in benchmarks.
+// Wildcards are left out, the benchmarks only use exact topics.
+type mutexRegistry struct {
+	subs map[string][]*subscriber
+	lock sync.Mutex
+}
+
+func (r *mutexRegistry) add(s *subscriber) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	subs := r.subs[s.key]
+	i, _ := slices.BinarySearchFunc(subs, s, compareSubscribers)
+	r.subs[s.key] = slices.Insert(subs, i, s)
+}
+
+func (r *mutexRegistry) remove(key string, id EventId) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	subs := r.subs[key]
+	i := slices.IndexFunc(subs, func(s *subscriber) bool { return s.id == id })
+	if i < 0 {
+		return
+	}
+
+	if len(subs) == 1 {
+		delete(r.subs, key)
+		return
+	}
+
+	r.subs[key] = slices.Delete(subs, i, i+1)
+}
+
+func (r *mutexRegistry) match(keys ...string) (subs []*subscriber) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, key := range keys {
+		subs = append(subs, r.subs[key]...)
+	}
+
+	if len(keys) > 1 {
+		slices.SortFunc(subs, compareSubscribers)
+	}
+
+	return
+}
+
+// cowRegistry serializes the writers of registry as the bus does.
+type cowRegistry struct {
+	*registry
+	lock sync.Mutex
+}
+
+func (r *cowRegistry) add(s *subscriber) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.registry.add(s)
+}
+
+func (r *cowRegistry) remove(key string, id EventId) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.registry.remove(key, id)
+}
+
+func (r *cowRegistry) match(keys ...string) []*subscriber {
+	return r.registry.match(keys[0], keys[1:]...)
+}
+
+type benchRegistry interface {
+	add(s *subscriber)
+	remove(key string, id EventId)
+	match(keys ...string) []*subscriber
+}
+
+const benchTopics = 64
+
+func benchmarkRegistry(b *testing.B, r benchRegistry, churn bool) {
+	var id atomic.Int64
+	newSub := func(key string) *subscriber {
+		return &subscriber{id: EventId(id.Add(1)), key: key}
+	}
+
+	for i := 0; i < benchTopics; i++ {
+		for j := 0; j < 4; j++ {
+			r.add(newSub(fmt.Sprintf("topic.%d", i)))
+		}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if churn {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				s := newSub(fmt.Sprintf("topic.%d", i%benchTopics))
+				r.add(s)
+				r.remove(s.key, s.id)
+			}
+		}()
+	}
+
+	var worker atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		topic := fmt.Sprintf("topic.%d", worker.Add(1)%benchTopics)
+		for pb.Next() {
+			r.match(topic, "<*event.benchmark>")
+		}
+	})
+	b.StopTimer()
+
+	close(done)
+	wg.Wait()
+}
+
+func BenchmarkRegistry(b *testing.B) {
+	for _, churn := range []bool{false, true} {
+		name := "publish"
+		if churn {
+			name = "publish+churn"
+		}
+
+		b.Run(name+"/mutex", func(b *testing.B) {
+			benchmarkRegistry(b, &mutexRegistry{subs: make(map[string][]*subscriber)}, churn)
+		})
+		b.Run(name+"/cow", func(b *testing.B) {
+			benchmarkRegistry(b, &cowRegistry{registry: newRegistry()}, churn)
+		})
+	}
+}
+
+func BenchmarkBus_PublishParallel(b *testing.B) {
+	ctx := context.Background()
+	bus := NewBus()
+
+	for i := 0; i < benchTopics; i++ {
+		bus.Subscribe(ctx, fmt.Sprintf("topic.%d", i), func(e Event) error { return nil })
+	}
+
+	var worker atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		topic := fmt.Sprintf("topic.%d", worker.Add(1)%benchTopics)
+		e := numberEvent{topic: topic}
+		for pb.Next() {
+			if err := bus.Publish(topic, e); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package event
+
+import (
+	"context"
+	goerrors "errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+// DeadLetterTopic is the default topic events are published on once a handler has exhausted its retries.
+const DeadLetterTopic = "event.dead_letter"
+
+// RetryPolicy configures retrying a failed handler with exponential backoff.
+//
+// Retries run on the goroutine of the publish, waiting for the backoff blocks the publisher and the
+// handlers after the retried one. On async buses it blocks the worker, and so the other topics it handles.
+// Keep backoffs short, or retry slow operations outside of the handler.
+type RetryPolicy struct {
+	// MaxAttempts is the number of calls including the first one.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, multiplied by Multiplier for each next retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Multiplier defaults to 2.
+	Multiplier float64
+	// Jitter randomizes each delay by up to the given fraction of it, between 0 and 1.
+	Jitter float64
+	// Retryable reports whether the error is worth retrying, nil retries all errors.
+	Retryable func(error) bool
+}
+
+// delay returns the delay before the given retry, starting from 1.
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	d := float64(p.Backoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 {
+		d = math.Min(d, float64(p.MaxBackoff))
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// DeadLetter is published once a handler has exhausted its retries, or an outbox entry is parked.
+type DeadLetter struct {
+	Event      Event
+	EventTopic string
+	// Subscriber is the id of the failed handler, zero for parked outbox entries.
+	Subscriber EventId
+	// Errors are the errors of each attempt in order.
+	Errors []error
+
+	topic string
+}
+
+func (d *DeadLetter) Topic() string {
+	return d.topic
+}
+
+// Err returns the errors of all attempts joined.
+func (d *DeadLetter) Err() error {
+	return goerrors.Join(d.Errors...)
+}
+
+// deliverRetry delivers the event, retrying on failure according to the policy of the subscriber.
+// Once retries are exhausted, or the subscriber has no policy but a dead-letter topic, a DeadLetter is published.
+func (b *Bus) deliverRetry(ctx context.Context, deliver DeliverFunc, d Delivery) *HandlerError {
+	policy := d.sub.retry
+
+	var errs []error
+	for d.Attempt = 1; ; d.Attempt++ {
+		herr := callHandler(ctx, deliver, d)
+		if herr == nil || errors.Is(herr.Err, ErrStopPropagation) {
+			return herr
+		}
+
+		errs = append(errs, herr.Err)
+
+		if policy == nil || d.Attempt >= policy.MaxAttempts || !policy.retryable(herr.Err) {
+			return b.deadLetter(ctx, d, herr, errs)
+		}
+
+		timer := time.NewTimer(policy.delay(d.Attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return b.deadLetter(ctx, d, herr, append(errs, ctx.Err()))
+		}
+	}
+}
+
+// deadLetter publishes the failed delivery to the dead-letter topic of the subscriber if any.
+// Failed dead letters, and events of the dead-letter topic itself, are not dead-lettered again,
+// otherwise a subscriber receiving its own dead letters would fail on them forever.
+func (b *Bus) deadLetter(ctx context.Context, d Delivery, herr *HandlerError, errs []error) *HandlerError {
+	if d.sub.deadLetter == "" || d.Topic == d.sub.deadLetter {
+		return herr
+	}
+
+	if _, ok := d.Event.(*DeadLetter); ok {
+		return herr
+	}
+
+	letter := &DeadLetter{
+		Event:      d.Event,
+		EventTopic: d.Topic,
+		Subscriber: d.Subscriber,
+		Errors:     errs,
+		topic:      d.sub.deadLetter,
+	}
+
+	if err := b.PublishContext(context.WithoutCancel(ctx), letter.topic, letter); err != nil {
+		herr.Err = goerrors.Join(herr.Err, errors.Wrapf(err, "failed to publish dead letter"))
+	}
+
+	return herr
+}
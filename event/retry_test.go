@@ -0,0 +1,109 @@
+package event
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+func TestBus_Retry(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+	errTemporary := errors.New("temporary")
+
+	attempts := 0
+	b.On("test").Retry(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}).Do(ctx, func(e Event) error {
+		attempts++
+		if attempts < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+
+	if err := b.Publish("test", &testEvent{}); err != nil {
+		t.Fatalf("Expected handler to succeed on third attempt, got %v", err)
+	}
+
+	if attempts != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestBus_DeadLetter(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+
+	attempts := 0
+	failing := b.On("test").Retry(RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     time.Millisecond,
+		Jitter:      0.5,
+		Retryable:   func(err error) bool { return !errors.Is(err, errPermanent) },
+	}).Do(ctx, func(e Event) error {
+		attempts++
+		if attempts < 2 {
+			return errTemporary
+		}
+		return errPermanent
+	})
+
+	var letter *DeadLetter
+	b.Subscribe(ctx, DeadLetterTopic, func(e Event) error {
+		letter = e.(*DeadLetter)
+		return nil
+	})
+
+	if err := b.Publish("test", &testEvent{}); !errors.Is(err, errPermanent) {
+		t.Fatalf("Expected %v, got %v", errPermanent, err)
+	}
+
+	if attempts != 2 {
+		t.Fatalf("Expected permanent error not to be retried, got %d attempts", attempts)
+	}
+
+	if letter == nil || letter.Subscriber != failing.Id() || letter.EventTopic != "test" || len(letter.Errors) != 2 {
+		t.Fatalf("Unexpected dead letter %+v", letter)
+	}
+
+	if !errors.Is(letter.Err(), errTemporary) || !errors.Is(letter.Err(), errPermanent) {
+		t.Fatalf("Expected dead letter to carry all errors, got %v", letter.Err())
+	}
+}
+
+func TestBus_DeadLetterLoop(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+
+	calls := 0
+	b.On("**").Retry(RetryPolicy{MaxAttempts: 1}).Do(ctx, func(e Event) error {
+		calls++
+		if calls > 10 {
+			t.Fatal("Expected dead letters not to be dead-lettered again")
+		}
+		return errors.New("failed")
+	})
+
+	if err := b.Publish("test", &testEvent{}); err == nil {
+		t.Fatal("Expected publish to fail")
+	}
+
+	// the event, then its dead letter which is not dead-lettered again
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+
+	calls = 0
+	b2 := NewBus()
+	b2.On(DeadLetterTopic).Retry(RetryPolicy{MaxAttempts: 1}).Do(ctx, func(e Event) error {
+		calls++
+		return errors.New("failed")
+	})
+
+	if err := b2.Publish(DeadLetterTopic, &testEvent{}); err == nil || calls != 1 {
+		t.Fatalf("Expected a single failed call on the dead-letter topic, got %d: %v", calls, err)
+	}
+}
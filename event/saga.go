@@ -0,0 +1,390 @@
+package event
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+// Correlated is an event belonging to a saga instance, identified by its correlation id.
+type Correlated interface {
+	Event
+	CorrelationId() string
+}
+
+// SagaStatus is the status of a saga instance.
+type SagaStatus string
+
+const (
+	SagaRunning     SagaStatus = "running"
+	SagaCompleted   SagaStatus = "completed"
+	SagaCompensated SagaStatus = "compensated"
+	// SagaFailed is the status of an instance whose compensation failed.
+	SagaFailed SagaStatus = "failed"
+)
+
+// SagaState is the persisted state of a saga instance.
+type SagaState struct {
+	Id     string
+	Status SagaStatus
+	// Step is the number of steps completed.
+	Step int
+	// Data is the state kept by the handlers between steps.
+	Data map[string]any
+	// Deadline is the time the event of the next step must arrive by, zero if none.
+	Deadline time.Time
+	// Err is the reason of the failure of the instance, if any.
+	Err string
+}
+
+// SagaStore persists the state of saga instances.
+type SagaStore interface {
+	// Load returns the state of the instance of the saga, ok is false if it does not exist.
+	Load(ctx context.Context, saga, id string) (state SagaState, ok bool, err error)
+
+	// Save creates or replaces the state of the instance of the saga.
+	Save(ctx context.Context, saga string, state SagaState) error
+
+	// Expired returns the running instances of the saga whose deadline is before now.
+	Expired(ctx context.Context, saga string, now time.Time) ([]SagaState, error)
+}
+
+// SagaInstance is a saga instance being handled.
+type SagaInstance struct {
+	SagaState
+	pending []sagaEvent
+}
+
+type sagaEvent struct {
+	topic string
+	event Event
+}
+
+// Publish queues a follow-up event, published once the state of the instance is saved.
+// Events queued by a failed step are dropped.
+func (s *SagaInstance) Publish(topic string, event Event) {
+	s.pending = append(s.pending, sagaEvent{topic: topic, event: event})
+}
+
+// SagaHandler handles an event of a saga instance.
+// Handlers must not publish to the bus directly, use SagaInstance.Publish.
+type SagaHandler func(ctx context.Context, s *SagaInstance, e Event) error
+
+// SagaStep is a step of a saga, advanced by an event of its topic.
+type SagaStep struct {
+	Topic string
+	// Handle is called with the event of the step to update the state and issue follow-up events.
+	// A failure compensates the completed steps.
+	Handle SagaHandler
+	// Compensate undoes the step when a later step fails. It receives the event failing the saga, nil on timeout.
+	Compensate SagaHandler
+	// Timeout is how long to wait for the event of the next step, after which the saga fails. 0 means forever.
+	Timeout time.Duration
+}
+
+// Saga is a process manager coordinating a sequence of steps across handlers, with compensation on failure.
+//
+// Each instance of the saga is identified by the correlation id of its events, see Correlated.
+// The event of the first step starts a new instance, events of later steps advance it in order.
+// Events out of order and events of finished instances are ignored.
+//
+// A step failing, an event of a FailOn topic or a step timing out fails the instance:
+// the completed steps are compensated in reverse order.
+type Saga struct {
+	name   string
+	bus    *Bus
+	store  SagaStore
+	steps  []SagaStep
+	failOn []string
+
+	// CheckInterval is how often Run checks for timed out instances. Defaults to a second.
+	CheckInterval time.Duration
+
+	onError func(error)
+	lock    sync.Mutex
+}
+
+// NewSaga creates a new saga keeping the state of its instances in the store.
+func NewSaga(name string, bus *Bus, store SagaStore) *Saga {
+	return &Saga{
+		name:          name,
+		bus:           bus,
+		store:         store,
+		CheckInterval: time.Second,
+	}
+}
+
+// Step appends a step to the saga.
+func (s *Saga) Step(step SagaStep) *Saga {
+	s.steps = append(s.steps, step)
+	return s
+}
+
+// FailOn fails the instances receiving events of the topics.
+func (s *Saga) FailOn(topics ...string) *Saga {
+	s.failOn = append(s.failOn, topics...)
+	return s
+}
+
+// SetErrorHandler sets the function called with errors of expiring instances in Run.
+func (s *Saga) SetErrorHandler(f func(error)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.onError = f
+}
+
+func (s *Saga) handleError(err error) {
+	s.lock.Lock()
+	f := s.onError
+	s.lock.Unlock()
+
+	if f != nil {
+		f(err)
+	}
+}
+
+// Subscribe subscribes the saga to the topics of its steps and FailOn until ctx is done.
+func (s *Saga) Subscribe(ctx context.Context) (subs []*Subscription) {
+	topics := make([]string, 0, len(s.steps)+len(s.failOn))
+	for _, step := range s.steps {
+		topics = append(topics, step.Topic)
+	}
+
+	topics = append(topics, s.failOn...)
+	slices.Sort(topics)
+
+	for _, topic := range slices.Compact(topics) {
+		topic := topic
+		subs = append(subs, s.bus.SubscribeContext(ctx, topic, func(ctx context.Context, e Event) error {
+			return s.handle(ctx, topic, e)
+		}))
+	}
+
+	return
+}
+
+// Run is a blocking function failing the timed out instances every CheckInterval until ctx is done.
+func (s *Saga) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if _, err := s.Expire(ctx); err != nil && ctx.Err() == nil {
+			s.handleError(err)
+		}
+	}
+}
+
+// Expire fails the instances whose step timed out. Returns the number of failed instances.
+func (s *Saga) Expire(ctx context.Context) (n int, err error) {
+	now := time.Now()
+
+	expired, err := s.store.Expired(ctx, s.name, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, state := range expired {
+		s.lock.Lock()
+		inst, err := s.expire(ctx, state.Id, now)
+		s.lock.Unlock()
+
+		if inst != nil {
+			n++
+		}
+
+		errs = append(errs, err, s.publish(ctx, inst))
+	}
+
+	return n, goerrors.Join(errs...)
+}
+
+// expire fails the instance if it is still running past its deadline.
+func (s *Saga) expire(ctx context.Context, id string, now time.Time) (*SagaInstance, error) {
+	state, ok, err := s.store.Load(ctx, s.name, id)
+	if err != nil || !ok || state.Status != SagaRunning || state.Deadline.IsZero() || state.Deadline.After(now) {
+		return nil, err
+	}
+
+	inst := &SagaInstance{SagaState: state}
+	cause := fmt.Errorf("%w: step %d of saga %s", ErrSagaTimeout, state.Step, s.name)
+
+	return inst, s.fail(ctx, inst, nil, cause)
+}
+
+// handle advances the instance of the event, then publishes its follow-up events.
+func (s *Saga) handle(ctx context.Context, topic string, e Event) error {
+	c, ok := e.(Correlated)
+	if !ok {
+		return nil
+	}
+
+	s.lock.Lock()
+	inst, err := s.advance(ctx, topic, c.CorrelationId(), e)
+	s.lock.Unlock()
+
+	if perr := s.publish(ctx, inst); perr != nil {
+		return goerrors.Join(err, perr)
+	}
+
+	return err
+}
+
+// advance runs the step of the event on the instance, or fails it.
+// Returns nil if the event is ignored.
+func (s *Saga) advance(ctx context.Context, topic, id string, e Event) (*SagaInstance, error) {
+	state, ok, err := s.store.Load(ctx, s.name, id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load instance %s of saga %s", id, s.name)
+	}
+
+	if !ok {
+		if len(s.steps) == 0 || s.steps[0].Topic != topic {
+			return nil, nil
+		}
+
+		state = SagaState{Id: id, Status: SagaRunning, Data: make(map[string]any)}
+	}
+
+	if state.Status != SagaRunning {
+		return nil, nil
+	}
+
+	inst := &SagaInstance{SagaState: state}
+
+	if slices.Contains(s.failOn, topic) {
+		return inst, s.fail(ctx, inst, e, fmt.Errorf("saga %s failed on %s", s.name, topic))
+	}
+
+	if state.Step >= len(s.steps) || s.steps[state.Step].Topic != topic {
+		return nil, nil
+	}
+
+	step := s.steps[state.Step]
+	if step.Handle != nil {
+		if err = step.Handle(ctx, inst, e); err != nil {
+			inst.pending = nil
+			return inst, goerrors.Join(err, s.fail(ctx, inst, e, err))
+		}
+	}
+
+	inst.Step++
+	inst.Deadline = time.Time{}
+
+	switch {
+	case inst.Step == len(s.steps):
+		inst.Status = SagaCompleted
+	case step.Timeout > 0:
+		inst.Deadline = time.Now().Add(step.Timeout)
+	}
+
+	return inst, s.save(ctx, inst)
+}
+
+// fail compensates the completed steps of the instance in reverse order and saves it.
+// Returns the errors of the compensations and saving.
+func (s *Saga) fail(ctx context.Context, inst *SagaInstance, e Event, cause error) error {
+	inst.Err = cause.Error()
+	inst.Deadline = time.Time{}
+	inst.Status = SagaCompensated
+
+	var errs []error
+	for i := inst.Step - 1; i >= 0; i-- {
+		compensate := s.steps[i].Compensate
+		if compensate == nil {
+			continue
+		}
+
+		if err := compensate(ctx, inst, e); err != nil {
+			inst.Status = SagaFailed
+			errs = append(errs, errors.Wrapf(err, "failed to compensate step %d of saga %s", i, s.name))
+		}
+	}
+
+	return goerrors.Join(append(errs, s.save(ctx, inst))...)
+}
+
+func (s *Saga) save(ctx context.Context, inst *SagaInstance) error {
+	if err := s.store.Save(ctx, s.name, inst.SagaState); err != nil {
+		inst.pending = nil
+		return errors.Wrapf(err, "failed to save instance %s of saga %s", inst.Id, s.name)
+	}
+
+	return nil
+}
+
+// publish publishes the follow-up events of the instance.
+func (s *Saga) publish(ctx context.Context, inst *SagaInstance) error {
+	if inst == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, p := range inst.pending {
+		errs = append(errs, s.bus.PublishContext(ctx, p.topic, p.event))
+	}
+
+	return goerrors.Join(errs...)
+}
+
+// MemorySagaStore is an in-memory SagaStore.
+type MemorySagaStore struct {
+	states map[string]map[string]SagaState
+	lock   sync.RWMutex
+}
+
+// NewMemorySagaStore creates a new empty MemorySagaStore.
+func NewMemorySagaStore() *MemorySagaStore {
+	return &MemorySagaStore{states: make(map[string]map[string]SagaState)}
+}
+
+func (m *MemorySagaStore) Load(_ context.Context, saga, id string) (SagaState, bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	state, ok := m.states[saga][id]
+	state.Data = maps.Clone(state.Data)
+
+	return state, ok, nil
+}
+
+func (m *MemorySagaStore) Save(_ context.Context, saga string, state SagaState) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.states[saga] == nil {
+		m.states[saga] = make(map[string]SagaState)
+	}
+
+	state.Data = maps.Clone(state.Data)
+	m.states[saga][state.Id] = state
+
+	return nil
+}
+
+func (m *MemorySagaStore) Expired(_ context.Context, saga string, now time.Time) (states []SagaState, err error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	for _, state := range m.states[saga] {
+		if state.Status == SagaRunning && !state.Deadline.IsZero() && state.Deadline.Before(now) {
+			state.Data = maps.Clone(state.Data)
+			states = append(states, state)
+		}
+	}
+
+	return
+}
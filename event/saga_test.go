@@ -0,0 +1,137 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type orderStep struct {
+	topic string
+	Order string
+}
+
+func (e *orderStep) Topic() string         { return e.topic }
+func (e *orderStep) CorrelationId() string { return e.Order }
+
+func testSaga(b *Bus, log *[]string) *Saga {
+	step := func(name, next string) SagaHandler {
+		return func(ctx context.Context, s *SagaInstance, e Event) error {
+			*log = append(*log, name)
+			if next != "" {
+				s.Publish(next, &orderStep{topic: next, Order: s.Id})
+			}
+			return nil
+		}
+	}
+	compensate := func(name string) SagaHandler {
+		return func(ctx context.Context, s *SagaInstance, e Event) error {
+			*log = append(*log, "undo "+name)
+			return nil
+		}
+	}
+
+	return NewSaga("order", b, NewMemorySagaStore()).
+		Step(SagaStep{Topic: "order.placed", Handle: step("reserve", "stock.reserve"), Compensate: compensate("reserve")}).
+		Step(SagaStep{Topic: "stock.reserved", Handle: step("charge", "card.charge"), Compensate: compensate("charge"), Timeout: 10 * time.Millisecond}).
+		Step(SagaStep{Topic: "card.charged", Handle: step("ship", "")}).
+		FailOn("card.declined")
+}
+
+func TestSaga(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+
+	var log []string
+	saga := testSaga(b, &log)
+	saga.Subscribe(ctx)
+
+	var charges []string
+	b.Subscribe(ctx, "stock.reserve", func(e Event) error {
+		return b.Publish("stock.reserved", &orderStep{topic: "stock.reserved", Order: e.(*orderStep).Order})
+	})
+	b.Subscribe(ctx, "card.charge", func(e Event) error {
+		order := e.(*orderStep).Order
+		charges = append(charges, order)
+		if order == "2" {
+			return b.Publish("card.declined", &orderStep{topic: "card.declined", Order: order})
+		}
+		if order == "1" {
+			return b.Publish("card.charged", &orderStep{topic: "card.charged", Order: order})
+		}
+		return nil
+	})
+
+	if err := b.Publish("order.placed", &orderStep{topic: "order.placed", Order: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"reserve", "charge", "ship"}; !slices.Equal(log, want) {
+		t.Fatalf("Expected %v, got %v", want, log)
+	}
+
+	state, _, _ := saga.store.Load(ctx, "order", "1")
+	if state.Status != SagaCompleted {
+		t.Fatalf("Expected completed saga, got %+v", state)
+	}
+
+	log = nil
+	if err := b.Publish("order.placed", &orderStep{topic: "order.placed", Order: "2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"reserve", "charge", "undo charge", "undo reserve"}; !slices.Equal(log, want) {
+		t.Fatalf("Expected %v, got %v", want, log)
+	}
+
+	// order 3 is never charged, the step times out
+	log = nil
+	if err := b.Publish("order.placed", &orderStep{topic: "order.placed", Order: "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if n, err := saga.Expire(ctx); err != nil || n != 1 {
+		t.Fatalf("Expected 1 expired instance, got %d: %v", n, err)
+	}
+
+	state, _, _ = saga.store.Load(ctx, "order", "3")
+	if want := []string{"reserve", "charge", "undo charge", "undo reserve"}; !slices.Equal(log, want) || state.Status != SagaCompensated {
+		t.Fatalf("Expected compensated timeout %v, got %v %+v", want, log, state)
+	}
+
+	// late events of finished instances are ignored
+	log = nil
+	if err := b.Publish("card.charged", &orderStep{topic: "card.charged", Order: "3"}); err != nil || len(log) != 0 {
+		t.Fatalf("Expected late event to be ignored, got %v: %v", log, err)
+	}
+}
+
+func TestSagaStepError(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+	failure := errors.New("out of stock")
+
+	var undone bool
+	NewSaga("order", b, NewMemorySagaStore()).
+		Step(SagaStep{Topic: "order.placed", Compensate: func(ctx context.Context, s *SagaInstance, e Event) error {
+			undone = true
+			return nil
+		}}).
+		Step(SagaStep{Topic: "stock.reserved", Handle: func(ctx context.Context, s *SagaInstance, e Event) error {
+			return failure
+		}}).
+		Subscribe(ctx)
+
+	if err := b.Publish("order.placed", &orderStep{topic: "order.placed", Order: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := b.Publish("stock.reserved", &orderStep{topic: "stock.reserved", Order: "1"})
+	if !errors.Is(err, failure) || !undone {
+		t.Fatalf("Expected failed step to be compensated and reported, got %v", err)
+	}
+}
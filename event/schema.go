@@ -0,0 +1,198 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/difof/errors"
+)
+
+// Versioned is an event declaring the name and version of its schema.
+// Versions start from 1 and increase by one for each change of the schema.
+type Versioned interface {
+	Event
+	SchemaName() string
+	SchemaVersion() int
+}
+
+// Upcaster transforms the fields of an encoded event from one version of its schema to the next.
+type Upcaster func(fields map[string]any) (map[string]any, error)
+
+// schema is the current version of a registered schema.
+type schema struct {
+	t       reflect.Type
+	version int
+}
+
+// schemaEnvelope is how SchemaRegistry encodes events.
+type schemaEnvelope struct {
+	Schema  string          `json:"schema"`
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// SchemaRegistry keeps the current version of event schemas and the upcasters from their older versions.
+//
+// It is a Codec encoding events as JSON along with their schema, and decoding older versions by upcasting
+// them to the current version. Events of unversioned payloads are decoded as version 1 of the schema
+// registered for their topic.
+//
+// Use Middleware to upcast old events in-flight before any handler sees them.
+type SchemaRegistry struct {
+	schemas   map[string]schema
+	topics    map[string]string
+	upcasters map[string]map[int]Upcaster
+	lock      sync.RWMutex
+}
+
+// NewSchemaRegistry creates a new SchemaRegistry with the given events registered as current versions.
+func NewSchemaRegistry(events ...Versioned) (r *SchemaRegistry) {
+	r = &SchemaRegistry{
+		schemas:   make(map[string]schema),
+		topics:    make(map[string]string),
+		upcasters: make(map[string]map[int]Upcaster),
+	}
+
+	for _, event := range events {
+		r.Register(event)
+	}
+
+	return
+}
+
+// Register registers the type of the event as the current version of its schema.
+func (r *SchemaRegistry) Register(event Versioned) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.schemas[event.SchemaName()] = schema{t: reflect.TypeOf(event), version: event.SchemaVersion()}
+	r.topics[event.Topic()] = event.SchemaName()
+}
+
+// Upcast registers the upcaster transforming version from of the schema to version from+1.
+func (r *SchemaRegistry) Upcast(name string, from int, upcaster Upcaster) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.upcasters[name] == nil {
+		r.upcasters[name] = make(map[int]Upcaster)
+	}
+
+	r.upcasters[name][from] = upcaster
+}
+
+func (r *SchemaRegistry) Encode(event Event) ([]byte, error) {
+	env := schemaEnvelope{Version: 1}
+
+	if v, ok := event.(Versioned); ok {
+		env.Schema = v.SchemaName()
+		env.Version = v.SchemaVersion()
+	} else {
+		r.lock.RLock()
+		env.Schema = r.topics[event.Topic()]
+		r.lock.RUnlock()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode event of %s", event.Topic())
+	}
+
+	env.Data = data
+
+	return json.Marshal(env)
+}
+
+func (r *SchemaRegistry) Decode(topic string, data []byte) (Event, error) {
+	var env schemaEnvelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Schema == "" || env.Data == nil {
+		r.lock.RLock()
+		env = schemaEnvelope{Schema: r.topics[topic], Version: 1, Data: data}
+		r.lock.RUnlock()
+	}
+
+	return r.decode(env)
+}
+
+// decode upcasts the envelope to the current version of its schema and decodes it.
+func (r *SchemaRegistry) decode(env schemaEnvelope) (Event, error) {
+	r.lock.RLock()
+	current, ok := r.schemas[env.Schema]
+	upcasters := r.upcasters[env.Schema]
+	r.lock.RUnlock()
+
+	if !ok {
+		return nil, errors.Newf("schema %q is not registered", env.Schema)
+	}
+
+	if env.Version > current.version {
+		return nil, errors.Newf("schema %q version %d is newer than the current version %d", env.Schema, env.Version, current.version)
+	}
+
+	data := []byte(env.Data)
+	if env.Version < current.version {
+		var fields map[string]any
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode schema %q version %d", env.Schema, env.Version)
+		}
+
+		for v := env.Version; v < current.version; v++ {
+			upcaster, ok := upcasters[v]
+			if !ok {
+				return nil, errors.Newf("no upcaster for schema %q version %d", env.Schema, v)
+			}
+
+			var err error
+			if fields, err = upcaster(fields); err != nil {
+				return nil, errors.Wrapf(err, "failed to upcast schema %q version %d", env.Schema, v)
+			}
+		}
+
+		var err error
+		if data, err = json.Marshal(fields); err != nil {
+			return nil, errors.Wrap(err)
+		}
+	}
+
+	return decodeJSON(current.t, data)
+}
+
+// UpcastEvent returns the event upcast to the current version of its schema.
+// Events which are not Versioned or are already current are returned as is.
+func (r *SchemaRegistry) UpcastEvent(event Event) (Event, error) {
+	v, ok := event.(Versioned)
+	if !ok {
+		return event, nil
+	}
+
+	r.lock.RLock()
+	current, ok := r.schemas[v.SchemaName()]
+	r.lock.RUnlock()
+
+	if !ok || v.SchemaVersion() >= current.version {
+		return event, nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode event of %s", event.Topic())
+	}
+
+	return r.decode(schemaEnvelope{Schema: v.SchemaName(), Version: v.SchemaVersion(), Data: data})
+}
+
+// Middleware returns a PublishMiddleware upcasting published events to the current version of their schema.
+func (r *SchemaRegistry) Middleware() PublishMiddleware {
+	return func(next PublishFunc) PublishFunc {
+		return func(ctx context.Context, topic string, event Event) error {
+			event, err := r.UpcastEvent(event)
+			if err != nil {
+				return err
+			}
+
+			return next(ctx, topic, event)
+		}
+	}
+}
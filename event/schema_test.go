@@ -0,0 +1,96 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+type accountOpenedV1 struct {
+	Name string
+}
+
+func (e *accountOpenedV1) Topic() string      { return "account.opened" }
+func (e *accountOpenedV1) SchemaName() string { return "account.opened" }
+func (e *accountOpenedV1) SchemaVersion() int { return 1 }
+
+type accountOpened struct {
+	First string
+	Last  string
+}
+
+func (e *accountOpened) Topic() string      { return "account.opened" }
+func (e *accountOpened) SchemaName() string { return "account.opened" }
+func (e *accountOpened) SchemaVersion() int { return 2 }
+
+func testSchemaRegistry() *SchemaRegistry {
+	r := NewSchemaRegistry(&accountOpened{})
+	r.Upcast("account.opened", 1, func(fields map[string]any) (map[string]any, error) {
+		fields["First"] = fields["Name"]
+		fields["Last"] = "unknown"
+		delete(fields, "Name")
+		return fields, nil
+	})
+
+	return r
+}
+
+func TestSchemaRegistryDecode(t *testing.T) {
+	r := testSchemaRegistry()
+
+	old, err := json.Marshal(schemaEnvelope{Schema: "account.opened", Version: 1, Data: []byte(`{"Name":"a"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range [][]byte{old, []byte(`{"Name":"a"}`)} {
+		e, err := r.Decode("account.opened", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := e.(*accountOpened); got.First != "a" || got.Last != "unknown" {
+			t.Fatalf("Expected upcast event, got %+v", got)
+		}
+	}
+
+	data, err := r.Encode(&accountOpened{First: "b", Last: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := r.Decode("account.opened", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := e.(*accountOpened); got.First != "b" || got.Last != "c" {
+		t.Fatalf("Expected round trip of current event, got %+v", got)
+	}
+
+	newer, _ := json.Marshal(schemaEnvelope{Schema: "account.opened", Version: 3, Data: []byte(`{}`)})
+	if _, err := r.Decode("account.opened", newer); err == nil {
+		t.Fatal("Expected error decoding a newer version")
+	}
+}
+
+func TestSchemaRegistryMiddleware(t *testing.T) {
+	ctx := context.Background()
+	r := testSchemaRegistry()
+	b := NewBus()
+	b.UsePublish(r.Middleware())
+
+	var got *accountOpened
+	Subscribe(b, func(ctx context.Context, e *accountOpened) error {
+		got = e
+		return nil
+	})
+
+	if err := Publish(ctx, b, &accountOpenedV1{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got == nil || got.First != "a" || got.Last != "unknown" {
+		t.Fatalf("Expected handler to receive upcast event, got %+v", got)
+	}
+}
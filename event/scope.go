@@ -0,0 +1,120 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Propagation sets which events are forwarded between a scoped bus and its parent.
+type Propagation int
+
+const (
+	// PropagateUp forwards events published on the child to the parent, prefixed by the namespace.
+	PropagateUp Propagation = 1 << iota
+	// PropagateDown forwards events published on the parent under the namespace to the child, without the prefix.
+	PropagateDown
+	// PropagateBoth forwards events both ways.
+	PropagateBoth = PropagateUp | PropagateDown
+)
+
+// scope links a child bus to its parent.
+type scope struct {
+	parent      *Bus
+	namespace   string
+	propagation Propagation
+}
+
+// prefix returns the topic of the child as seen on the parent.
+func (s *scope) prefix(topic string) string {
+	return s.namespace + "/" + topic
+}
+
+// scopeKey marks the context of a forwarded publish with the bus it was forwarded from.
+type scopeKey struct{}
+
+// Scope creates a child bus scoped to the namespace, forwarding events according to the propagation:
+// "user.created" published on the child appears as "<namespace>/user.created" on the parent and vice versa.
+// Scopes can be nested, namespaces add up on the way to the root.
+//
+// The child is torn down when ctx is done, see Teardown.
+func (b *Bus) Scope(ctx context.Context, namespace string, propagation Propagation) (child *Bus) {
+	child = NewBus()
+	child.scope.Store(&scope{parent: b, namespace: namespace, propagation: propagation})
+
+	b.lock.Lock()
+	children := append(slices.Clip(b.loadChildren()), child)
+	b.children.Store(&children)
+	b.lock.Unlock()
+
+	context.AfterFunc(ctx, child.Teardown)
+
+	return
+}
+
+// Teardown detaches a scoped bus from its parent, tears down its own scoped buses
+// and removes all of its subscriptions at once. Handlers already running are not interrupted.
+func (b *Bus) Teardown() {
+	b.lock.Lock()
+	s := b.scope.Swap(nil)
+	children := b.loadChildren()
+	b.lock.Unlock()
+
+	if s != nil {
+		s.parent.lock.Lock()
+		siblings := slices.DeleteFunc(slices.Clone(s.parent.loadChildren()), func(c *Bus) bool { return c == b })
+		s.parent.children.Store(&siblings)
+		s.parent.lock.Unlock()
+	}
+
+	for _, child := range children {
+		child.Teardown()
+	}
+
+	for _, sub := range b.subscribers.all() {
+		sub.handle.Unsubscribe()
+	}
+}
+
+// loadChildren returns the scoped buses of the bus. The returned slice must not be modified.
+func (b *Bus) loadChildren() []*Bus {
+	if children := b.children.Load(); children != nil {
+		return *children
+	}
+
+	return nil
+}
+
+// propagate forwards the event to the parent and children of the bus, except to the bus it was forwarded from.
+// Returns the errors of the forwarded publishes.
+func (b *Bus) propagate(ctx context.Context, topic string, event Event) error {
+	from, _ := ctx.Value(scopeKey{}).(*Bus)
+
+	s := b.scope.Load()
+	children := b.loadChildren()
+
+	if s == nil && len(children) == 0 {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, scopeKey{}, b)
+
+	var errs []error
+	if s != nil && s.propagation&PropagateUp != 0 && s.parent != from {
+		errs = append(errs, s.parent.publish(ctx, s.prefix(topic), event))
+	}
+
+	for _, child := range children {
+		cs := child.scope.Load()
+		if child == from || cs == nil || cs.propagation&PropagateDown == 0 {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(topic, cs.namespace+"/"); ok {
+			errs = append(errs, child.publish(ctx, rest, event))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,76 @@
+package event
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	ctx := context.Background()
+	app := NewBus()
+	auth := app.Scope(ctx, "auth", PropagateBoth)
+	audit := app.Scope(ctx, "audit", PropagateUp)
+
+	var onApp, onAuth, onAudit []string
+	app.Subscribe(ctx, "auth/user.created", func(e Event) error {
+		onApp = append(onApp, e.(*userCreated).Name)
+		return nil
+	})
+	auth.Subscribe(ctx, "user.created", func(e Event) error {
+		onAuth = append(onAuth, e.(*userCreated).Name)
+		return nil
+	})
+	audit.Subscribe(ctx, "user.created", func(e Event) error {
+		onAudit = append(onAudit, e.(*userCreated).Name)
+		return nil
+	})
+
+	// up from the child, without echoing back into it
+	if err := auth.Publish("user.created", &userCreated{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// down from the parent
+	if err := app.Publish("auth/user.created", &userCreated{Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// audit does not propagate down
+	if err := app.Publish("audit/user.created", &userCreated{Name: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(onApp) != 2 || len(onAuth) != 2 || len(onAudit) != 0 {
+		t.Fatalf("Unexpected deliveries: app %v, auth %v, audit %v", onApp, onAuth, onAudit)
+	}
+
+	sub := auth.Subscribe(ctx, "user.deleted", func(e Event) error { return nil })
+	session := auth.Scope(ctx, "session", PropagateBoth)
+	nested := session.Subscribe(ctx, "user.created", func(e Event) error { return nil })
+	auth.Teardown()
+
+	for _, s := range []*Subscription{sub, nested} {
+		select {
+		case <-s.Done():
+		default:
+			t.Fatal("Expected teardown to remove all subscriptions, including of nested scopes")
+		}
+	}
+
+	// up from the torn down child
+	if err := auth.Publish("user.created", &userCreated{Name: "e"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(onApp) != 2 {
+		t.Fatalf("Expected no propagation up after teardown, got %v", onApp)
+	}
+
+	if err := app.Publish("auth/user.created", &userCreated{Name: "d"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(onAuth) != 2 {
+		t.Fatalf("Expected no deliveries after teardown, got %v", onAuth)
+	}
+}
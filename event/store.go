@@ -0,0 +1,85 @@
+package event
+
+import (
+	"context"
+	goerrors "errors"
+	"math"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+// Record is an event persisted in a Store.
+type Record struct {
+	Seq   uint64
+	Topic string
+	Time  time.Time
+	Event Event
+}
+
+// Store is an append-only log of events. Sequence numbers start from 1 and are
+// assigned in the order events are appended, regardless of their topics.
+type Store interface {
+	// Append persists the event and returns its sequence number.
+	Append(ctx context.Context, topic string, event Event) (uint64, error)
+
+	// Read calls f for each record of the topic with a sequence number of at least from, in order.
+	// Reads all topics if topic is empty. Stops at the first error returned by f.
+	Read(ctx context.Context, topic string, from uint64, f func(Record) error) error
+}
+
+type replayKey struct{}
+
+// RecordFrom returns the record being replayed if ctx belongs to a replay, see Replay.
+func RecordFrom(ctx context.Context) (Record, bool) {
+	r, ok := ctx.Value(replayKey{}).(Record)
+	return r, ok
+}
+
+// Persist appends the events published on the topics to the store until ctx is done.
+// Topics may be wildcard patterns, events are stored with the topic they were published on.
+// Events are appended before any other handler is called. If appending fails, the remaining handlers
+// are skipped so projections do not drift from the store, and Publish fails with the error.
+// Replayed events are not appended again.
+func Persist(ctx context.Context, bus *Bus, store Store, topics ...string) []*Subscription {
+	subs := make([]*Subscription, 0, len(topics))
+
+	for _, topic := range topics {
+		subs = append(subs, bus.On(topic).Priority(math.MaxInt).DoContext(ctx, func(ctx context.Context, e Event) (err error) {
+			if _, ok := RecordFrom(ctx); ok {
+				return
+			}
+
+			topic, _ := TopicFrom(ctx)
+
+			if _, err = store.Append(ctx, topic, e); err != nil {
+				return goerrors.Join(errors.Wrapf(err, "failed to persist event of %s", topic), ErrStopPropagation)
+			}
+
+			return
+		}))
+	}
+
+	return subs
+}
+
+// Replay publishes the records of the topic from the store into the bus, starting at sequence number from.
+// Replays all topics if topic is empty. Handlers can tell replayed events apart using RecordFrom.
+// Returns the sequence number of the last replayed record, or stops at the first publish error.
+func Replay(ctx context.Context, bus *Bus, store Store, topic string, from uint64) (last uint64, err error) {
+	err = store.Read(ctx, topic, from, func(r Record) error {
+		if err := bus.PublishContext(context.WithValue(ctx, replayKey{}, r), r.Topic, r.Event); err != nil {
+			return errors.Wrapf(err, "failed to replay record %d", r.Seq)
+		}
+
+		last = r.Seq
+		return nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	err = bus.Flush(ctx)
+	return
+}
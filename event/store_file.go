@@ -0,0 +1,170 @@
+package event
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+// fileRecord is a line of a FileStore.
+type fileRecord struct {
+	Seq   uint64    `json:"seq"`
+	Topic string    `json:"topic"`
+	Time  time.Time `json:"time"`
+	Data  []byte    `json:"data"`
+}
+
+// FileStore is a Store backed by an append-only file of JSON lines, encoding events with a Codec.
+// Every append is synced to disk before returning.
+type FileStore struct {
+	file  *os.File
+	path  string
+	codec Codec
+	seq   uint64
+	size  int64
+	lock  sync.Mutex
+}
+
+// OpenFileStore opens or creates the store file at path.
+// A partially written last line, left by a crash, is truncated.
+func OpenFileStore(path string, codec Codec) (s *FileStore, err error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open event store %s", path)
+	}
+
+	s = &FileStore{file: file, path: path, codec: codec}
+
+	err = readFileRecords(file, -1, func(r fileRecord, end int64) error {
+		s.seq = r.Seq
+		s.size = end
+		return nil
+	})
+
+	if err == nil {
+		err = file.Truncate(s.size)
+	}
+
+	if err == nil {
+		_, err = file.Seek(s.size, io.SeekStart)
+	}
+
+	if err != nil {
+		_ = file.Close()
+		return nil, errors.Wrapf(err, "failed to load event store %s", path)
+	}
+
+	return
+}
+
+// readFileRecords calls f with each complete record of r and the offset it ends at, up to limit bytes if not negative.
+func readFileRecords(r io.Reader, limit int64, f func(fileRecord, int64) error) error {
+	return readLines(r, limit, func(line []byte, offset, end int64) error {
+		var rec fileRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return errors.Wrapf(err, "corrupted record at offset %d", offset)
+		}
+
+		return f(rec, end)
+	})
+}
+
+// readLines calls f with each complete line of r, the offset it starts at and the offset it ends at,
+// up to limit bytes if not negative. An unterminated last line is a torn write and is skipped.
+func readLines(r io.Reader, limit int64, f func(line []byte, offset, end int64) error) error {
+	if limit >= 0 {
+		r = io.LimitReader(r, limit)
+	}
+
+	reader := bufio.NewReader(r)
+	offset := int64(0)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		end := offset + int64(len(line))
+		if err = f(line, offset, end); err != nil {
+			return err
+		}
+
+		offset = end
+	}
+}
+
+func (s *FileStore) Append(_ context.Context, topic string, event Event) (uint64, error) {
+	data, err := s.codec.Encode(event)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to encode event of %s", topic)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rec := fileRecord{Seq: s.seq + 1, Topic: topic, Time: time.Now(), Data: data}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return 0, errors.Wrap(err)
+	}
+
+	line = append(line, '\n')
+	if _, err = s.file.Write(line); err != nil {
+		return 0, errors.Wrapf(err, "failed to append record %d", rec.Seq)
+	}
+
+	if err = s.file.Sync(); err != nil {
+		return 0, errors.Wrapf(err, "failed to sync record %d", rec.Seq)
+	}
+
+	s.seq = rec.Seq
+	s.size += int64(len(line))
+
+	return rec.Seq, nil
+}
+
+func (s *FileStore) Read(ctx context.Context, topic string, from uint64, f func(Record) error) error {
+	s.lock.Lock()
+	size := s.size
+	s.lock.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open event store %s", s.path)
+	}
+	defer file.Close()
+
+	return readFileRecords(file, size, func(rec fileRecord, _ int64) error {
+		if rec.Seq < from || topic != "" && rec.Topic != topic {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		event, err := s.codec.Decode(rec.Topic, rec.Data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode record %d", rec.Seq)
+		}
+
+		return f(Record{Seq: rec.Seq, Topic: rec.Topic, Time: rec.Time, Event: event})
+	})
+}
+
+// Close closes the store file.
+func (s *FileStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,50 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store.
+type MemoryStore struct {
+	records []Record
+	lock    sync.RWMutex
+}
+
+// NewMemoryStore creates a new empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Append(_ context.Context, topic string, event Event) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	seq := uint64(len(s.records) + 1)
+	s.records = append(s.records, Record{Seq: seq, Topic: topic, Time: time.Now(), Event: event})
+
+	return seq, nil
+}
+
+func (s *MemoryStore) Read(ctx context.Context, topic string, from uint64, f func(Record) error) error {
+	s.lock.RLock()
+	records := s.records
+	s.lock.RUnlock()
+
+	for _, r := range records[min(max(from, 1), uint64(len(records)+1))-1:] {
+		if topic != "" && r.Topic != topic {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := f(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
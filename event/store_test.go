@@ -0,0 +1,209 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type userDeleted struct {
+	Name string
+}
+
+func (e *userDeleted) Topic() string {
+	return "user.deleted"
+}
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	b := NewBus()
+	Persist(ctx, b, store, "user.created", "user.deleted")
+
+	for _, e := range []Event{&userCreated{Name: "a"}, &userCreated{Name: "b"}, &userDeleted{Name: "a"}} {
+		if err := Publish(ctx, b, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	err := store.Read(ctx, "user.created", 2, func(r Record) error {
+		names = append(names, r.Event.(*userCreated).Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 1 || names[0] != "b" {
+		t.Fatalf("Expected to read user b from position 2, got %v", names)
+	}
+
+	// replaying into the same bus must not persist the events again
+	users := map[string]bool{}
+	b.SubscribeContext(ctx, "user.created", func(ctx context.Context, e Event) error {
+		if _, ok := RecordFrom(ctx); !ok {
+			t.Error("Expected replayed event to carry its record")
+		}
+		users[e.(*userCreated).Name] = true
+		return nil
+	})
+	b.Subscribe(ctx, "user.deleted", func(e Event) error {
+		delete(users, e.(*userDeleted).Name)
+		return nil
+	})
+
+	last, err := Replay(ctx, b, store, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last != 3 || len(users) != 1 || !users["b"] {
+		t.Fatalf("Expected projection with user b after replaying 3 records, got %v after %d", users, last)
+	}
+
+	if seq, _ := store.Append(ctx, "user.created", &userCreated{Name: "c"}); seq != 4 {
+		t.Fatalf("Expected replayed events not to be persisted again, got sequence %d", seq)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	codec := NewJSONCodec(&userCreated{}, &userDeleted{})
+
+	store, err := OpenFileStore(path, codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testStore(t, store)
+
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a torn write
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString(`{"seq":5,"top`)
+	_ = file.Close()
+
+	store, err = OpenFileStore(path, codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if seq, err := store.Append(context.Background(), "user.created", &userCreated{Name: "d"}); err != nil || seq != 5 {
+		t.Fatalf("Expected sequence to continue at 5 after reopening, got %d (%v)", seq, err)
+	}
+}
+
+// failingStore is a Store whose appends always fail.
+type failingStore struct {
+	MemoryStore
+}
+
+func (s *failingStore) Append(context.Context, string, Event) (uint64, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestPersist_AppendError(t *testing.T) {
+	ctx := context.Background()
+	b := NewBus()
+	Persist(ctx, b, &failingStore{}, "user.created")
+
+	projected := false
+	b.Subscribe(ctx, "user.created", func(e Event) error {
+		projected = true
+		return nil
+	})
+
+	err := Publish(ctx, b, &userCreated{Name: "a"})
+
+	var perr *PublishError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected publish to fail with the append error, got %v", err)
+	}
+
+	if projected {
+		t.Fatal("Expected handlers after a failed append to be skipped")
+	}
+}
+
+func TestPersist_Wildcard(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	b := NewBus()
+	Persist(ctx, b, store, "user.*")
+	if err := Publish(ctx, b, &userCreated{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := NewBus()
+	var names []string
+	replayed.Subscribe(ctx, "user.created", func(e Event) error {
+		names = append(names, e.(*userCreated).Name)
+		return nil
+	})
+
+	if _, err := Replay(ctx, replayed, store, "user.created", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 1 || names[0] != "a" {
+		t.Fatalf("Expected the event to be stored and replayed on its own topic, got %v", names)
+	}
+}
+
+func TestFileStore_Scoped(t *testing.T) {
+	ctx := context.Background()
+	codec := NewJSONCodec(&userCreated{})
+
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "events.log"), codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	app := NewBus()
+	shop := app.Scope(ctx, "shop", PropagateUp)
+	Persist(ctx, app, store, "**")
+
+	if err = Publish(ctx, shop, &userCreated{Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = app.Publish("signup", &userCreated{Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := NewBus()
+	var topics []string
+	replayed.SubscribeContext(ctx, "**", func(ctx context.Context, e Event) error {
+		topic, _ := TopicFrom(ctx)
+		topics = append(topics, topic+":"+e.(*userCreated).Name)
+		return nil
+	})
+
+	if _, err = Replay(ctx, replayed, store, "", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(topics) != 2 || topics[0] != "shop/user.created:a" || topics[1] != "signup:b" {
+		t.Fatalf("Expected events replayed on the topics they were published on, got %v", topics)
+	}
+
+	// bare payloads are decoded by the topic
+	if e, err := codec.Decode("user.created", []byte(`{"Name":"c"}`)); err != nil || e.(*userCreated).Name != "c" {
+		t.Fatalf("Expected bare payload to be decoded, got %v (%v)", e, err)
+	}
+}
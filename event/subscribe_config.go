@@ -0,0 +1,144 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// SubscribeConfig is responsible for configuring a subscription and adding it to the bus.
+type SubscribeConfig struct {
+	bus      *Bus
+	topic    string
+	priority int
+	timeout  time.Duration
+	times    int
+	limited  bool
+	retry    *RetryPolicy
+	// deadLetter is the topic failed events are published on, if any.
+	deadLetter string
+	debounce   time.Duration
+	throttle   time.Duration
+	window     time.Duration
+	key        func(Event) string
+}
+
+// On begins configuring a subscription to the topic. Call SubscribeConfig.Do to subscribe.
+func (b *Bus) On(topic string) *SubscribeConfig {
+	return &SubscribeConfig{
+		bus:   b,
+		topic: topic,
+	}
+}
+
+// Priority sets the priority of the handler. Handlers with higher priority are called first,
+// handlers with the same priority are called in the order they subscribed. Defaults to 0.
+func (c *SubscribeConfig) Priority(priority int) *SubscribeConfig {
+	c.priority = priority
+	return c
+}
+
+// Timeout sets how long the handler may take. A handler exceeding its timeout is abandoned
+// and reported with a *TimeoutError, its context is cancelled to let it return.
+func (c *SubscribeConfig) Timeout(timeout time.Duration) *SubscribeConfig {
+	c.timeout = timeout
+	return c
+}
+
+// Times limits the subscription to n events, after which it is removed.
+// The subscription is removed right away if n <= 0.
+func (c *SubscribeConfig) Times(n int) *SubscribeConfig {
+	c.times = n
+	c.limited = true
+	return c
+}
+
+// Retry retries the handler on failure according to the policy. Once the retries are exhausted, the event
+// is published as a *DeadLetter on DeadLetterTopic, unless another topic is set with DeadLetter.
+// The backoff blocks the publish, see RetryPolicy.
+func (c *SubscribeConfig) Retry(policy RetryPolicy) *SubscribeConfig {
+	c.retry = &policy
+	if c.deadLetter == "" {
+		c.deadLetter = DeadLetterTopic
+	}
+	return c
+}
+
+// DeadLetter sets the topic failed events are published on as a *DeadLetter, after exhausting retries if any.
+func (c *SubscribeConfig) DeadLetter(topic string) *SubscribeConfig {
+	c.deadLetter = topic
+	return c
+}
+
+// Debounce delivers only the last event of a burst, once no event arrived for delay.
+// Each topic published is debounced on its own, so wildcard subscriptions receive the last event of every topic.
+// The handler is called after the publish returns, or right away by Bus.Drain and Bus.Close,
+// through the handler middleware and with the timeout and retries of the subscription.
+// Its errors are passed to the function set by Bus.SetErrorHandler.
+func (c *SubscribeConfig) Debounce(delay time.Duration) *SubscribeConfig {
+	c.debounce = delay
+	return c
+}
+
+// Throttle delivers at most one event per interval, dropping the events in between.
+// The interval is shared by all topics of wildcard subscriptions.
+func (c *SubscribeConfig) Throttle(interval time.Duration) *SubscribeConfig {
+	c.throttle = interval
+	return c
+}
+
+// Coalesce collects the events of each window into a batch for the handler of DoBatch, keeping only
+// the last event of each key in the order the keys first appeared. A nil key keeps all events.
+// Each topic published is batched on its own.
+// The handler is called after the window ends, as with Debounce.
+func (c *SubscribeConfig) Coalesce(window time.Duration, key func(Event) string) *SubscribeConfig {
+	c.window = window
+	c.key = key
+	return c
+}
+
+// Do subscribes the handler to the topic.
+// removes subscription when context is done before receiving event
+func (c *SubscribeConfig) Do(ctx context.Context, handler EventHandler) *Subscription {
+	return c.DoContext(ctx, func(_ context.Context, e Event) error { return handler(e) })
+}
+
+// DoContext subscribes the handler receiving the context of the publisher to the topic.
+// removes subscription when context is done before receiving event
+func (c *SubscribeConfig) DoContext(ctx context.Context, handler ContextHandler) *Subscription {
+	s := c.subscriber()
+	s.handler = handler
+
+	switch {
+	case c.debounce > 0:
+		s.pacer = &debouncer{bus: c.bus, delay: c.debounce}
+	case c.throttle > 0:
+		s.pacer = &throttler{interval: c.throttle}
+	}
+
+	return newSubscription(c.bus, s).watch(ctx)
+}
+
+// DoBatch subscribes the handler of batches of events to the topic, see Coalesce.
+// Without Coalesce, every event is a batch of its own.
+func (c *SubscribeConfig) DoBatch(ctx context.Context, handler BatchHandler) *Subscription {
+	s := c.subscriber()
+	co := &coalescer{bus: c.bus, handler: handler, window: c.window, key: c.key}
+	s.handler, s.pacer = co.handle, co
+
+	return newSubscription(c.bus, s).watch(ctx)
+}
+
+// subscriber creates the subscriber of the configuration without its handler.
+func (c *SubscribeConfig) subscriber() *subscriber {
+	s := &subscriber{
+		key:        c.topic,
+		priority:   c.priority,
+		timeout:    c.timeout,
+		retry:      c.retry,
+		limited:    c.limited,
+		deadLetter: c.deadLetter,
+	}
+	s.remaining.Store(int64(c.times))
+
+	return s
+}
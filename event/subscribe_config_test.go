@@ -0,0 +1,136 @@
+package event
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/difof/errors"
+)
+
+type orderEvent struct {
+	CancellableEvent
+}
+
+func (e *orderEvent) Topic() string {
+	return "order"
+}
+
+func TestBus_Ordering(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+
+	var order []int
+	record := func(n int) EventHandler {
+		return func(e Event) error {
+			order = append(order, n)
+			return nil
+		}
+	}
+
+	b.Subscribe(ctx, "order", record(2))
+	b.Subscribe(ctx, "order", record(3))
+	b.On("order").Priority(10).Do(ctx, record(1))
+	b.On("order").Priority(-1).Do(ctx, record(4))
+
+	for i := 0; i < 5; i++ {
+		order = order[:0]
+		if err := b.Publish("order", &orderEvent{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(order, []int{1, 2, 3, 4}) {
+			t.Fatalf("Expected handlers to be called in order, got %v", order)
+		}
+	}
+}
+
+func TestBus_StopPropagation(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+
+	called := 0
+	b.Subscribe(ctx, "order", func(e Event) error {
+		called++
+		return ErrStopPropagation
+	})
+	b.Subscribe(ctx, "order", func(e Event) error {
+		called++
+		return nil
+	})
+
+	if err := b.Publish("order", &orderEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if called != 1 {
+		t.Fatalf("Expected propagation to stop after first handler, got %d calls", called)
+	}
+}
+
+func TestBus_Cancel(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+	errInvalid := errors.New("invalid")
+
+	called := 0
+	validator := b.Subscribe(ctx, "order", func(e Event) error {
+		e.(Cancellable).Cancel(errInvalid)
+		return nil
+	})
+	b.Subscribe(ctx, "order", func(e Event) error {
+		called++
+		return nil
+	})
+
+	err := b.Publish("order", &orderEvent{})
+	if !errors.Is(err, ErrCancelled) || !errors.Is(err, errInvalid) {
+		t.Fatalf("Expected cancelled error, got %v", err)
+	}
+
+	var cerr *CancelledError
+	if !errors.As(err, &cerr) || cerr.Id != validator.Id() {
+		t.Fatalf("Expected event to be cancelled by handler %d, got %v", validator.Id(), err)
+	}
+
+	if called != 0 {
+		t.Fatal("Expected handlers after cancellation not to be called")
+	}
+}
+
+func TestBus_Timeout(t *testing.T) {
+	b := NewBus()
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	hung := b.On("test").Timeout(20*time.Millisecond).DoContext(ctx, func(ctx context.Context, e Event) error {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Error("Expected handler context to be cancelled on timeout")
+		}
+		return nil
+	})
+
+	called := false
+	b.SubscribeContext(ctx, "test", func(ctx context.Context, e Event) error {
+		called = ctx.Value(ctxKey{}) == "value"
+		return nil
+	})
+
+	err := b.PublishContext(ctx, "test", &testEvent{})
+
+	var terr *TimeoutError
+	if !errors.As(err, &terr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+
+	var perr *PublishError
+	if !errors.As(err, &perr) || perr.Errors[0].Id != hung.Id() {
+		t.Fatalf("Expected handler %d to time out, got %v", hung.Id(), err)
+	}
+
+	if !called {
+		t.Fatal("Expected next handler to be called with the publisher context")
+	}
+}
@@ -0,0 +1,74 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// Subscription is the handle of a handler subscribed to the bus.
+type Subscription struct {
+	bus  *Bus
+	sub  *subscriber
+	stop func() bool
+	once sync.Once
+	done chan struct{}
+}
+
+func newSubscription(bus *Bus, sub *subscriber) (s *Subscription) {
+	s = &Subscription{
+		bus:  bus,
+		sub:  sub,
+		done: make(chan struct{}),
+	}
+
+	sub.handle = s
+	bus.subscribe(sub)
+
+	// subscriptions of a closed bus, or limited to no events, are removed right away, see Bus.Close
+	if bus.closed.Load() || sub.limited && sub.remaining.Load() <= 0 {
+		s.remove()
+	}
+
+	return
+}
+
+// watch unsubscribes when ctx is done. Does not cost a goroutine for contexts of the context package.
+func (s *Subscription) watch(ctx context.Context) *Subscription {
+	s.stop = context.AfterFunc(ctx, s.remove)
+	return s
+}
+
+// remove removes the subscriber from the bus once.
+func (s *Subscription) remove() {
+	s.once.Do(func() {
+		s.sub.removed.Store(true)
+		s.bus.unsubscribe(s.sub)
+		close(s.done)
+	})
+}
+
+// Id returns the id of the subscription.
+func (s *Subscription) Id() EventId {
+	return s.sub.id
+}
+
+// Topic returns the topic of the subscription.
+func (s *Subscription) Topic() string {
+	return s.sub.key
+}
+
+// Unsubscribe removes the handler from the bus. Safe to call multiple times.
+// Handlers already running are not interrupted.
+func (s *Subscription) Unsubscribe() {
+	if s.stop != nil {
+		s.stop()
+	}
+
+	s.remove()
+}
+
+// Done returns a channel that is closed when the subscription is removed,
+// either by Unsubscribe, its context or by receiving all of its events.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
@@ -0,0 +1,110 @@
+package event
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBus_SubscribeN(t *testing.T) {
+	b := NewBus()
+	ctx := context.Background()
+
+	once, n := 0, 0
+	onceSub := b.Once(ctx, "test", func(e Event) error {
+		once++
+		return nil
+	})
+	b.SubscribeN(ctx, "test", 3, func(e Event) error {
+		n++
+		return nil
+	})
+
+	for i := 0; i < 5; i++ {
+		if err := b.Publish("test", &testEvent{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if once != 1 || n != 3 {
+		t.Fatalf("Expected 1 and 3 deliveries, got %d and %d", once, n)
+	}
+
+	select {
+	case <-onceSub.Done():
+	default:
+		t.Fatal("Expected Once subscription to be removed after its event")
+	}
+
+	zero := b.SubscribeN(ctx, "test", 0, func(e Event) error {
+		t.Error("Expected subscription of no events not to be called")
+		return nil
+	})
+
+	select {
+	case <-zero.Done():
+	default:
+		t.Fatal("Expected subscription of no events to be removed right away")
+	}
+
+	if err := b.Publish("test", &testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBus_UnsubscribeDeprecated(t *testing.T) {
+	b := NewBus()
+
+	called := 0
+	sub := b.Subscribe(context.Background(), "test", func(e Event) error {
+		called++
+		return nil
+	})
+
+	b.Unsubscribe("test", sub.Id(), nil)
+
+	if err := b.Publish("test", &testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if called != 0 {
+		t.Fatal("Expected unsubscribed handler not to be called")
+	}
+
+	select {
+	case <-sub.Done():
+	default:
+		t.Fatal("Expected subscription to be removed")
+	}
+}
+
+func TestSubscription_Unsubscribe(t *testing.T) {
+	b := NewBus()
+
+	called := 0
+	sub := b.Subscribe(context.Background(), "test", func(e Event) error {
+		called++
+		return nil
+	})
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+
+	if err := b.Publish("test", &testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if called != 0 {
+		t.Fatal("Expected unsubscribed handler not to be called")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub = b.Subscribe(ctx, "test", func(e Event) error { return nil })
+	cancel()
+
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected subscription to be removed with its context")
+	}
+}
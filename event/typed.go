@@ -0,0 +1,70 @@
+package event
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// typeKeys caches the subscriber keys of event types.
+var typeKeys sync.Map
+
+// typeKey returns the key which handlers of the concrete type of the event are subscribed on.
+func typeKey(event Event) string {
+	return typeKeyOf(reflect.TypeOf(event))
+}
+
+func typeKeyOf(t reflect.Type) string {
+	if key, ok := typeKeys.Load(t); ok {
+		return key.(string)
+	}
+
+	var b strings.Builder
+	b.WriteString("<")
+
+	elem := t
+	for elem.Kind() == reflect.Pointer {
+		b.WriteString("*")
+		elem = elem.Elem()
+	}
+
+	if elem.Name() != "" {
+		b.WriteString(elem.PkgPath() + "." + elem.Name())
+	} else {
+		b.WriteString(elem.String())
+	}
+
+	b.WriteString(">")
+
+	key, _ := typeKeys.LoadOrStore(t, b.String())
+	return key.(string)
+}
+
+// Subscribe adds a handler for events of type T, no matter which topic they are published on.
+// The handler is never called with events of other types.
+//
+// T must be a concrete type, events are matched by their exact type.
+// Panics if T is an interface such as Event, use a wildcard topic to receive events of many types.
+func Subscribe[T Event](bus *Bus, handler func(context.Context, T) error) *Subscription {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Interface {
+		panic("event: Subscribe of interface type " + t.String())
+	}
+
+	return newSubscription(bus, &subscriber{
+		key: typeKeyOf(t),
+		handler: func(ctx context.Context, e Event) error {
+			return handler(ctx, e.(T))
+		},
+	})
+}
+
+// Publish publishes the event on its own topic, to subscribers of the topic and of the type of the event.
+func Publish(ctx context.Context, bus *Bus, event Event) error {
+	if event == nil {
+		return nil
+	}
+
+	return bus.publish(unmarked(ctx), event.Topic(), event)
+}
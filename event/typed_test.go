@@ -0,0 +1,76 @@
+package event
+
+import (
+	"context"
+	"testing"
+)
+
+type userCreated struct {
+	Name string
+}
+
+func (e *userCreated) Topic() string {
+	return "user.created"
+}
+
+type ctxKey struct{}
+
+func TestPublish_Typed(t *testing.T) {
+	b := NewBus()
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	var typed *userCreated
+	sub := Subscribe(b, func(ctx context.Context, e *userCreated) error {
+		if ctx.Value(ctxKey{}) != "value" {
+			t.Error("Expected context of publisher")
+		}
+		typed = e
+		return nil
+	})
+
+	topicReceived := 0
+	b.Subscribe(ctx, "user.created", func(e Event) error {
+		topicReceived++
+		return nil
+	})
+
+	if err := Publish(ctx, b, &userCreated{Name: "john"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// events of other types are not delivered to typed handlers
+	if err := b.Publish("user.created", &testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if typed == nil || typed.Name != "john" {
+		t.Fatalf("Expected typed handler to receive the event, got %v", typed)
+	}
+
+	if topicReceived != 2 {
+		t.Fatalf("Expected topic handler to receive 2 events, got %d", topicReceived)
+	}
+
+	sub.Unsubscribe()
+	typed = nil
+
+	if err := Publish(ctx, b, &userCreated{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if typed != nil {
+		t.Fatal("Expected unsubscribed typed handler not to be called")
+	}
+}
+
+func TestSubscribe_Interface(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected Subscribe of an interface type to panic")
+		}
+	}()
+
+	Subscribe(NewBus(), func(ctx context.Context, e Versioned) error {
+		return nil
+	})
+}